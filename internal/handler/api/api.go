@@ -1,11 +1,14 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
+	"github.com/bots-house/webshot/internal/renderer"
 	"github.com/getsentry/sentry-go"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
 )
 
 func handleError(h func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
@@ -15,6 +18,16 @@ func handleError(h func(w http.ResponseWriter, r *http.Request) error) http.Hand
 		err := h(w, r)
 
 		if err != nil {
+			// nobody to respond
+			if ctx.Err() == context.Canceled && xerrors.Is(err, context.Canceled) {
+				log.Ctx(ctx).Warn().Err(err).Msg("client disconnected")
+				return
+			}
+
+			if xerrors.Is(err, renderer.ErrRenderTimeout) {
+				err = httpError(err, http.StatusGatewayTimeout)
+			}
+
 			switch e := err.(type) {
 			case *HTTPError:
 				w.WriteHeader(e.Code)
@@ -36,3 +49,14 @@ func handleError(h func(w http.ResponseWriter, r *http.Request) error) http.Hand
 		}
 	})
 }
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return xerrors.Errorf("encode response: %w", err)
+	}
+
+	return nil
+}
@@ -29,15 +29,32 @@ func NewAuthHMAC(key string, signParam string) *AuthHMAC {
 	}
 }
 
+// Allow checks signature of request params.
+// When form is parsed, body params are signed too.
 func (auth *AuthHMAC) Allow(ctx context.Context, r *http.Request) error {
 	qs := r.URL.Query()
 
+	if r.Form != nil {
+		qs = make(url.Values, len(r.Form))
+		for k, v := range r.Form {
+			qs[k] = v
+		}
+	}
+
 	sign := qs.Get(auth.signParam)
 	qs.Del(auth.signParam)
 
 	return auth.validHMAC(qs, sign)
 }
 
+// Sign returns hex encoded HMAC-SHA256 of payload.
+func (auth *AuthHMAC) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(auth.key))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // ValidMAC reports whether messageMAC is a valid HMAC tag for message.
 func (auth *AuthHMAC) validHMAC(params url.Values, signature string) error {
 	keys := make([]string, 0, len(params))
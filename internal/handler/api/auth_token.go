@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrInvalidToken = xerrors.New("invalid token")
+)
+
+// AuthToken checks static token passed as 'Authorization: Bearer <token>' header.
+// It's used by admin API, where request body can't be signed by HMAC of params.
+type AuthToken struct {
+	token string
+}
+
+func NewAuthToken(token string) *AuthToken {
+	return &AuthToken{token: token}
+}
+
+func (auth *AuthToken) Allow(ctx context.Context, r *http.Request) error {
+	const prefix = "Bearer "
+
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return ErrInvalidToken
+	}
+
+	token := strings.TrimPrefix(header, prefix)
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(auth.token)) != 1 {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/schema"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+
+	"github.com/bots-house/webshot/internal/service"
+)
+
+const (
+	// maxBatchBodySize limits size of batch request, HTML of items is included.
+	maxBatchBodySize = 20 << 20
+
+	defaultBatchMaxItems = 200
+
+	batchManifestName = "manifest.json"
+)
+
+// BatchOpts defines limits of batch handler.
+type BatchOpts struct {
+	// Number of concurrent renders of batch.
+	Parallelism int
+
+	// Max number of items in batch. Default is 200.
+	MaxItems int
+}
+
+func (opts *BatchOpts) getMaxItems() int {
+	if opts.MaxItems <= 0 {
+		return defaultBatchMaxItems
+	}
+
+	return opts.MaxItems
+}
+
+// BatchInput is body of batch request.
+// Params have same names as params of image handler,
+// params of item override shared ones.
+type BatchInput struct {
+	Params BatchParams       `json:"params"`
+	Items  []*BatchItemInput `json:"items"`
+}
+
+type BatchItemInput struct {
+	URL    string      `json:"url"`
+	Params BatchParams `json:"params"`
+}
+
+// BatchParams contains params of screenshot.
+// Arrays are treated as repeated params, nulls are ignored.
+type BatchParams map[string]interface{}
+
+func (params BatchParams) addTo(values url.Values) {
+	for k, v := range params {
+		values.Del(k)
+
+		if v == nil {
+			continue
+		}
+
+		if items, ok := v.([]interface{}); ok {
+			for _, item := range items {
+				values.Add(k, fmt.Sprint(item))
+			}
+		} else {
+			values.Set(k, fmt.Sprint(v))
+		}
+	}
+}
+
+// decodeScreenshotInput decodes params, which are not passed as request.
+func decodeScreenshotInput(values url.Values) (*ScreenshotInput, error) {
+	input := &ScreenshotInput{}
+
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+
+	if err := decoder.Decode(input, values); err != nil {
+		return nil, xerrors.Errorf("decode params: %w", err)
+	}
+
+	if input.URL == "" && input.HTML == "" {
+		return nil, xerrors.Errorf("url or html is required")
+	}
+
+	return input, nil
+}
+
+// ParseShotParams converts params in format of batch item to options of service.
+func ParseShotParams(targetURL string, params map[string]interface{}) (service.ShotOpts, error) {
+	values := url.Values{}
+
+	BatchParams(params).addTo(values)
+
+	if targetURL != "" {
+		values.Set("url", targetURL)
+	}
+
+	input, err := decodeScreenshotInput(values)
+	if err != nil {
+		return service.ShotOpts{}, err
+	}
+
+	return input.shotOpts()
+}
+
+// BatchManifestItem describes result of item in manifest.json.
+type BatchManifestItem struct {
+	Index  int    `json:"index"`
+	URL    string `json:"url,omitempty"`
+	File   string `json:"file,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Cache  string `json:"cache,omitempty"`
+
+	// Duration in milliseconds
+	Took int64 `json:"took"`
+}
+
+// parseBatchItems converts input to items of service.
+// Each item is authorized as separate image request, so signature of item covers merged params.
+func parseBatchItems(r *http.Request, input *BatchInput, auth Auth) ([]service.BatchItem, error) {
+	ctx := r.Context()
+
+	items := make([]service.BatchItem, len(input.Items))
+
+	for i, item := range input.Items {
+		if item == nil {
+			return nil, httpError(xerrors.Errorf("item #%d: item is null", i), http.StatusUnprocessableEntity)
+		}
+
+		values := url.Values{}
+
+		input.Params.addTo(values)
+		item.Params.addTo(values)
+
+		if item.URL != "" {
+			values.Set("url", item.URL)
+		}
+
+		screenshotInput, err := decodeScreenshotInput(values)
+		if err != nil {
+			err = xerrors.Errorf("item #%d: %w", i, err)
+			return nil, httpError(err, http.StatusUnprocessableEntity)
+		}
+
+		if auth != nil {
+			itemReq := &http.Request{URL: &url.URL{}, Form: values}
+
+			if err := auth.Allow(ctx, itemReq); err != nil {
+				err = xerrors.Errorf("item #%d: unathorized: %w", i, err)
+				return nil, httpError(err, http.StatusUnauthorized)
+			}
+		}
+
+		opts, err := screenshotInput.shotOpts()
+		if err != nil {
+			err = xerrors.Errorf("item #%d: %w", i, err)
+			return nil, httpError(err, http.StatusUnprocessableEntity)
+		}
+
+		items[i] = service.BatchItem{
+			URL:  screenshotInput.URL,
+			Opts: opts,
+		}
+	}
+
+	return items, nil
+}
+
+// NewBatchHandler renders list of screenshots and streams them as ZIP archive with manifest.json.
+func NewBatchHandler(srv *service.Service, auth Auth, opts BatchOpts) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		input := &BatchInput{}
+
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+
+		// keep numbers as is, e.g. 2592000 instead of 2.592e+06
+		decoder.UseNumber()
+
+		if err := decoder.Decode(input); err != nil {
+			err = xerrors.Errorf("decode body: %w", err)
+			return httpError(err, http.StatusBadRequest)
+		}
+
+		if len(input.Items) == 0 {
+			err := xerrors.Errorf("items are required")
+			return httpError(err, http.StatusUnprocessableEntity)
+		}
+
+		if len(input.Items) > opts.getMaxItems() {
+			err := xerrors.Errorf("too many items, max is %d", opts.getMaxItems())
+			return httpError(err, http.StatusUnprocessableEntity)
+		}
+
+		items, err := parseBatchItems(r, input, auth)
+		if err != nil {
+			return err
+		}
+
+		started := time.Now()
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="webshot.zip"`)
+
+		archive := zip.NewWriter(w)
+
+		manifest := make([]*BatchManifestItem, len(items))
+		failed := 0
+
+		// response is started, so errors of items are reported in manifest
+		err = srv.ShotBatch(ctx, items, opts.Parallelism, func(result *service.BatchResult) error {
+			item := &BatchManifestItem{
+				Index:  result.Index,
+				URL:    result.Item.URL,
+				Status: "done",
+				Cache:  string(result.Cache),
+				Took:   result.Took.Milliseconds(),
+			}
+
+			manifest[result.Index] = item
+
+			if result.Err != nil {
+				item.Status = "failed"
+				item.Error = result.Err.Error()
+				failed++
+
+				return nil
+			}
+
+			item.File = fmt.Sprintf("%03d.%s", result.Index+1, result.Item.Opts.Render.Format.Ext())
+
+			f, err := archive.CreateHeader(&zip.FileHeader{
+				Name:     item.File,
+				Method:   zip.Store,
+				Modified: time.Now(),
+			})
+			if err != nil {
+				return xerrors.Errorf("create file: %w", err)
+			}
+
+			if _, err := f.Write(result.Body); err != nil {
+				return xerrors.Errorf("write file: %w", err)
+			}
+
+			return nil
+		})
+
+		logger := log.Ctx(ctx)
+
+		if err != nil {
+			logger.Warn().Err(err).Msg("batch aborted")
+			return nil
+		}
+
+		f, err := archive.Create(batchManifestName)
+		if err != nil {
+			logger.Error().Err(err).Msg("create manifest")
+			return nil
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(manifest); err != nil {
+			logger.Error().Err(err).Msg("write manifest")
+			return nil
+		}
+
+		if err := archive.Close(); err != nil {
+			logger.Error().Err(err).Msg("close archive")
+			return nil
+		}
+
+		logger.Info().
+			Int("items", len(items)).
+			Int("failed", failed).
+			Dur("took", time.Since(started)).
+			Msg("batch finished")
+
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/schema"
+	"golang.org/x/xerrors"
+
+	"github.com/bots-house/webshot/internal/service"
+	"github.com/bots-house/webshot/internal/storage"
+)
+
+// PurgeInput selects cached screenshots to purge.
+// When only url is passed, other params are same as params of image handler.
+type PurgeInput struct {
+	URL string `schema:"url"`
+
+	// Purge screenshots of url with any params
+	All bool `schema:"all"`
+
+	// Purge all screenshots of sites with hostname
+	Host string `schema:"host"`
+}
+
+// PurgeOutput is result of purge.
+type PurgeOutput struct {
+	Deleted int `json:"deleted"`
+
+	// Screenshots of host, which can't be matched by url
+	Unindexed int    `json:"unindexed,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+}
+
+// NewCacheDeleteHandler purges cached screenshots, auth of admin API is required.
+func NewCacheDeleteHandler(srv *service.Service, auth Auth) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		if err := r.ParseForm(); err != nil {
+			err = xerrors.Errorf("parse form: %w", err)
+			return httpError(err, http.StatusBadRequest)
+		}
+
+		input := &PurgeInput{}
+
+		decoder := schema.NewDecoder()
+		decoder.IgnoreUnknownKeys(true)
+
+		if err := decoder.Decode(input, r.Form); err != nil {
+			err = xerrors.Errorf("decode form: %w", err)
+			return httpError(err, http.StatusUnprocessableEntity)
+		}
+
+		if err := auth.Allow(ctx, r); err != nil {
+			err = xerrors.Errorf("unathorized: %w", err)
+			return httpError(err, http.StatusUnauthorized)
+		}
+
+		query := service.PurgeQuery{
+			URL:     input.URL,
+			AllOpts: input.All,
+			Host:    input.Host,
+		}
+
+		switch {
+		case input.Host != "":
+		case input.URL == "":
+			err := xerrors.Errorf("url or host is required")
+			return httpError(err, http.StatusUnprocessableEntity)
+		case !input.All:
+			screenshotInput, err := decodeScreenshotInput(r.Form)
+			if err != nil {
+				return httpError(err, http.StatusUnprocessableEntity)
+			}
+
+			opts, err := screenshotInput.shotOpts()
+			if err != nil {
+				return err
+			}
+
+			query.Opts = &opts.Render
+		}
+
+		result, err := srv.Purge(ctx, query)
+		if xerrors.Is(err, storage.ErrInvalidQuery) {
+			return httpError(err, http.StatusUnprocessableEntity)
+		} else if err != nil {
+			return xerrors.Errorf("purge: %w", err)
+		}
+
+		output := &PurgeOutput{
+			Deleted:   result.Deleted,
+			Unindexed: result.Unindexed,
+		}
+
+		if output.Unindexed > 0 {
+			output.Hint = "screenshots cached before url was indexed are not matched, purge them by host"
+		}
+
+		return writeJSON(w, http.StatusOK, output)
+	})
+}
@@ -2,7 +2,10 @@ package api
 
 import (
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/schema"
@@ -14,7 +17,14 @@ import (
 )
 
 type ScreenshotInput struct {
-	URL string `schema:"url,required"`
+	// URL of target page or base URL of HTML
+	URL string `schema:"url"`
+
+	// HTML to render, can be passed as form field or as text/html body of POST request
+	HTML string `schema:"html"`
+
+	Device    string `schema:"device"`
+	Landscape bool   `schema:"landscape"`
 
 	Width  int     `schema:"width"`
 	Height int     `schema:"height"`
@@ -27,84 +37,372 @@ type ScreenshotInput struct {
 	FullPage   bool `schema:"full_page"`
 	Delay      int  `schema:"delay"`
 
+	WaitUntil        renderer.WaitUntil `schema:"wait_until"`
+	WaitIdleTime     int                `schema:"wait_idle_time"`
+	WaitIdleInflight int                `schema:"wait_idle_inflight"`
+	WaitForSelector  string             `schema:"wait_for_selector"`
+	WaitForFunction  string             `schema:"wait_for_function"`
+	WaitTimeout      int                `schema:"wait_timeout"`
+
+	// Max duration of render in milliseconds
+	Timeout int `schema:"timeout"`
+
+	JS            string   `schema:"js"`
+	CSS           string   `schema:"css"`
+	HideSelectors []string `schema:"hide_selectors"`
+
+	// Cookies in Set-Cookie format, e.g. 'name=value; Domain=example.com; Path=/'
+	Cookies []string `schema:"cookie"`
+
+	// Headers in 'Name: value' format
+	Headers []string `schema:"header"`
+
+	UserAgent string `schema:"user_agent"`
+
+	ColorScheme   renderer.ColorScheme `schema:"color_scheme"`
+	ReducedMotion bool                 `schema:"reduced_motion"`
+	Media         renderer.Media       `schema:"media"`
+
+	Timezone     string   `schema:"timezone"`
+	Locale       string   `schema:"locale"`
+	GeoLatitude  *float64 `schema:"geo_latitude"`
+	GeoLongitude *float64 `schema:"geo_longitude"`
+	GeoAccuracy  float64  `schema:"geo_accuracy"`
+
+	BlockResources []renderer.Resource `schema:"block_resources"`
+
 	ClipX      *float64 `schema:"clip_x"`
 	ClipY      *float64 `schema:"clip_y"`
 	ClipWidth  *float64 `schema:"clip_width"`
 	ClipHeight *float64 `schema:"clip_height"`
 
+	Selector        string  `schema:"selector"`
+	SelectorPadding float64 `schema:"selector_padding"`
+
+	PDFPaperWidth      float64  `schema:"pdf_paper_width"`
+	PDFPaperHeight     float64  `schema:"pdf_paper_height"`
+	PDFMarginTop       *float64 `schema:"pdf_margin_top"`
+	PDFMarginBottom    *float64 `schema:"pdf_margin_bottom"`
+	PDFMarginLeft      *float64 `schema:"pdf_margin_left"`
+	PDFMarginRight     *float64 `schema:"pdf_margin_right"`
+	PDFLandscape       bool     `schema:"pdf_landscape"`
+	PDFPageRanges      string   `schema:"pdf_page_ranges"`
+	PDFPrintBackground bool     `schema:"pdf_print_background"`
+	PDFHeaderTemplate  string   `schema:"pdf_header_template"`
+	PDFFooterTemplate  string   `schema:"pdf_footer_template"`
+
 	Fresh bool `schema:"fresh"`
 	TTL   int  `schema:"ttl"`
+
+	// Window in seconds after TTL, when expired image is served and refreshed in background
+	StaleTTL *int `schema:"stale_ttl"`
+
+	// How cached image is returned, default of server is used when empty
+	Response ResponseMode `schema:"response"`
+}
+
+// ResponseMode defines how cached image is returned.
+type ResponseMode string
+
+const (
+	// ResponseBody returns image in response body.
+	ResponseBody ResponseMode = "body"
+
+	// ResponseRedirect redirects to public URL of cached image,
+	// rendered image is returned in response body.
+	ResponseRedirect ResponseMode = "redirect"
+)
+
+func (mode ResponseMode) Validate() error {
+	switch mode {
+	case ResponseBody, ResponseRedirect:
+		return nil
+	default:
+		return xerrors.Errorf("unknown response mode '%s'", mode)
+	}
 }
 
-func NewImageHandler(srv *service.Service, auth Auth) http.HandlerFunc {
+// maxHTMLBodySize limits size of HTML passed in request body.
+const maxHTMLBodySize = 5 << 20
+
+// isHTMLBody reports whether HTML to render is passed as request body.
+func isHTMLBody(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	return err == nil && contentType == "text/html"
+}
+
+// NewImageHandler takes screenshot, cached image is returned according to response mode,
+// which can be overridden by request.
+func NewImageHandler(srv *service.Service, auth Auth, response ResponseMode) http.HandlerFunc {
 	return handleError(func(w http.ResponseWriter, r *http.Request) error {
 		ctx := r.Context()
 
-		if err := r.ParseForm(); err != nil {
-			err = xerrors.Errorf("parse form: %w", err)
-			return httpError(err, http.StatusBadRequest)
+		input, err := parseScreenshotInput(w, r, auth)
+		if err != nil {
+			return err
 		}
 
-		input := &ScreenshotInput{}
-
-		decoder := schema.NewDecoder()
-		decoder.IgnoreUnknownKeys(true)
+		if input.Response == "" {
+			input.Response = response
+		}
 
-		if err := decoder.Decode(input, r.Form); err != nil {
-			err = xerrors.Errorf("decode form: %w", err)
+		if err := input.Response.Validate(); err != nil {
 			return httpError(err, http.StatusUnprocessableEntity)
 		}
 
-		if auth != nil {
-			if err := auth.Allow(ctx, r); err != nil {
-				err = xerrors.Errorf("unathorized: %w", err)
-				return httpError(err, http.StatusUnauthorized)
-			}
+		opts, err := input.shotOpts()
+		if err != nil {
+			return err
 		}
 
-		renderOpts := renderer.Opts{
-			Width:      input.Width,
-			Height:     input.Height,
-			Scale:      input.Scale,
-			Format:     input.Format,
-			Quality:    input.Quality,
-			Delay:      time.Millisecond * time.Duration(input.Delay),
-			FullPage:   input.FullPage,
-			ScrollPage: input.ScrollPage,
-			Clip: renderer.OptsClip{
-				X:      input.ClipX,
-				Y:      input.ClipY,
-				Width:  input.ClipWidth,
-				Height: input.ClipHeight,
-			},
-		}
+		// cached image is not downloaded to redirect to it
+		opts.Cache.URLOnly = input.Response == ResponseRedirect
 
-		if err := renderOpts.Validate(); err != nil {
-			err = xerrors.Errorf("validate opts: %w", err)
-			return httpError(err, http.StatusUnprocessableEntity)
+		output, err := srv.Shot(ctx, input.URL, opts)
+		if err != nil {
+			return renderError(err)
 		}
 
-		cacheOpts := service.CacheOpts{
-			TTL:   time.Second * time.Duration(input.TTL),
-			Fresh: input.Fresh,
+		if input.Response == ResponseRedirect && output.Cache != service.CacheMiss && output.URL != "" {
+			writeRedirect(w, r, output)
+			return nil
 		}
 
-		output, err := srv.Shot(ctx, input.URL, service.ShotOpts{
-			Render: renderOpts,
-			Cache:  cacheOpts,
-		})
+		return writeResult(w, output, opts.Render.Format)
+	})
+}
+
+// parseScreenshotInput decodes and authorizes screenshot request.
+func parseScreenshotInput(w http.ResponseWriter, r *http.Request, auth Auth) (*ScreenshotInput, error) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		err = xerrors.Errorf("parse form: %w", err)
+		return nil, httpError(err, http.StatusBadRequest)
+	}
 
+	if isHTMLBody(r) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTMLBodySize))
 		if err != nil {
-			return xerrors.Errorf("render error: %w", err)
+			err = xerrors.Errorf("read body: %w", err)
+			return nil, httpError(err, http.StatusBadRequest)
 		}
 
-		w.Header().Set("Content-Type", renderOpts.Format.ContentType())
+		r.Form.Set("html", string(body))
+	}
 
-		_, err = io.Copy(w, output)
-		if err != nil {
-			return xerrors.Errorf("copy output: %w", err)
+	input := &ScreenshotInput{}
+
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+
+	if err := decoder.Decode(input, r.Form); err != nil {
+		err = xerrors.Errorf("decode form: %w", err)
+		return nil, httpError(err, http.StatusUnprocessableEntity)
+	}
+
+	if input.URL == "" && input.HTML == "" {
+		err := xerrors.Errorf("url or html is required")
+		return nil, httpError(err, http.StatusUnprocessableEntity)
+	}
+
+	if auth != nil {
+		if err := auth.Allow(ctx, r); err != nil {
+			err = xerrors.Errorf("unathorized: %w", err)
+			return nil, httpError(err, http.StatusUnauthorized)
 		}
+	}
 
-		return nil
-	})
+	return input, nil
+}
+
+// shotOpts converts input to options of service.
+func (input *ScreenshotInput) shotOpts() (service.ShotOpts, error) {
+	cookies, err := parseCookies(input.Cookies)
+	if err != nil {
+		err = xerrors.Errorf("parse cookies: %w", err)
+		return service.ShotOpts{}, httpError(err, http.StatusUnprocessableEntity)
+	}
+
+	headers, err := parseHeaders(input.Headers)
+	if err != nil {
+		err = xerrors.Errorf("parse headers: %w", err)
+		return service.ShotOpts{}, httpError(err, http.StatusUnprocessableEntity)
+	}
+
+	renderOpts := renderer.Opts{
+		HTML: input.HTML,
+
+		Device:     input.Device,
+		Landscape:  input.Landscape,
+		Width:      input.Width,
+		Height:     input.Height,
+		Scale:      input.Scale,
+		Format:     input.Format,
+		Quality:    input.Quality,
+		Delay:      time.Millisecond * time.Duration(input.Delay),
+		FullPage:   input.FullPage,
+		ScrollPage: input.ScrollPage,
+
+		WaitUntil:           input.WaitUntil,
+		NetworkIdleTime:     time.Millisecond * time.Duration(input.WaitIdleTime),
+		NetworkIdleInflight: input.WaitIdleInflight,
+		WaitForSelector:     input.WaitForSelector,
+		WaitForFunction:     input.WaitForFunction,
+		WaitTimeout:         time.Millisecond * time.Duration(input.WaitTimeout),
+		Timeout:             time.Millisecond * time.Duration(input.Timeout),
+
+		JS:            input.JS,
+		CSS:           input.CSS,
+		HideSelectors: input.HideSelectors,
+
+		Cookies:   cookies,
+		Headers:   headers,
+		UserAgent: input.UserAgent,
+
+		ColorScheme:   input.ColorScheme,
+		ReducedMotion: input.ReducedMotion,
+		Media:         input.Media,
+
+		Timezone: input.Timezone,
+		Locale:   input.Locale,
+		Geolocation: renderer.OptsGeolocation{
+			Latitude:  input.GeoLatitude,
+			Longitude: input.GeoLongitude,
+			Accuracy:  input.GeoAccuracy,
+		},
+
+		BlockResources: input.BlockResources,
+
+		Clip: renderer.OptsClip{
+			X:      input.ClipX,
+			Y:      input.ClipY,
+			Width:  input.ClipWidth,
+			Height: input.ClipHeight,
+		},
+		Selector:        input.Selector,
+		SelectorPadding: input.SelectorPadding,
+		PDF: renderer.OptsPDF{
+			PaperWidth:      input.PDFPaperWidth,
+			PaperHeight:     input.PDFPaperHeight,
+			MarginTop:       input.PDFMarginTop,
+			MarginBottom:    input.PDFMarginBottom,
+			MarginLeft:      input.PDFMarginLeft,
+			MarginRight:     input.PDFMarginRight,
+			Landscape:       input.PDFLandscape,
+			PageRanges:      input.PDFPageRanges,
+			PrintBackground: input.PDFPrintBackground,
+			HeaderTemplate:  input.PDFHeaderTemplate,
+			FooterTemplate:  input.PDFFooterTemplate,
+		},
+	}
+
+	if err := renderOpts.Validate(); err != nil {
+		err = xerrors.Errorf("validate opts: %w", err)
+		return service.ShotOpts{}, httpError(err, http.StatusUnprocessableEntity)
+	}
+
+	cacheOpts := service.CacheOpts{
+		TTL:   time.Second * time.Duration(input.TTL),
+		Fresh: input.Fresh,
+	}
+
+	if input.StaleTTL != nil {
+		staleTTL := time.Second * time.Duration(*input.StaleTTL)
+		cacheOpts.StaleTTL = &staleTTL
+	}
+
+	return service.ShotOpts{
+		Render: renderOpts,
+		Cache:  cacheOpts,
+	}, nil
+}
+
+// writeResult writes image with cache status headers.
+func writeResult(w http.ResponseWriter, result *service.Result, format internal.ImageFormat) error {
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("X-Cache", string(result.Cache))
+
+	if result.Cache != service.CacheMiss {
+		w.Header().Set("Age", strconv.Itoa(int(result.Age.Seconds())))
+	}
+
+	if _, err := io.Copy(w, result.Body); err != nil {
+		return xerrors.Errorf("copy output: %w", err)
+	}
+
+	return nil
+}
+
+// writeRedirect redirects to public URL of cached image with cache status headers.
+func writeRedirect(w http.ResponseWriter, r *http.Request, result *service.Result) {
+	w.Header().Set("X-Cache", string(result.Cache))
+	w.Header().Set("Age", strconv.Itoa(int(result.Age.Seconds())))
+
+	http.Redirect(w, r, result.URL, http.StatusFound)
+}
+
+// renderError maps errors caused by page or render options to client errors.
+func renderError(err error) error {
+	switch {
+	case xerrors.Is(err, renderer.ErrElementNotFound),
+		xerrors.Is(err, renderer.ErrWaitTimeout),
+		xerrors.Is(err, renderer.ErrScriptFailed):
+		return httpError(err, http.StatusUnprocessableEntity)
+	default:
+		return xerrors.Errorf("render error: %w", err)
+	}
+}
+
+// parseCookies parses cookies in Set-Cookie format.
+func parseCookies(values []string) ([]renderer.OptsCookie, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	res := &http.Response{Header: http.Header{"Set-Cookie": values}}
+
+	cookies := res.Cookies()
+	if len(cookies) != len(values) {
+		return nil, xerrors.Errorf("invalid cookie format, expected 'name=value; Domain=...; Path=...'")
+	}
+
+	result := make([]renderer.OptsCookie, len(cookies))
+
+	for i, cookie := range cookies {
+		result[i] = renderer.OptsCookie{
+			Name:   cookie.Name,
+			Value:  cookie.Value,
+			Domain: cookie.Domain,
+			Path:   cookie.Path,
+		}
+	}
+
+	return result, nil
+}
+
+// parseHeaders parses headers in 'Name: value' format.
+func parseHeaders(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	headers := make(map[string]string, len(values))
+
+	for _, v := range values {
+		idx := strings.Index(v, ":")
+		if idx <= 0 {
+			return nil, xerrors.Errorf("invalid header format, expected 'Name: value'")
+		}
+
+		name := http.CanonicalHeaderKey(strings.TrimSpace(v[:idx]))
+		headers[name] = strings.TrimSpace(v[idx+1:])
+	}
+
+	return headers, nil
 }
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/schema"
+	"golang.org/x/xerrors"
+
+	"github.com/bots-house/webshot/internal/jobs"
+)
+
+// JobOutput is representation of job in API.
+type JobOutput struct {
+	ID     string      `json:"id"`
+	URL    string      `json:"url,omitempty"`
+	Status jobs.Status `json:"status"`
+	Error  string      `json:"error,omitempty"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// Path of result, when job is done
+	Result string `json:"result,omitempty"`
+}
+
+func newJobOutput(job *jobs.Job) *JobOutput {
+	out := &JobOutput{
+		ID:        job.ID,
+		URL:       job.URL,
+		Status:    job.Status,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+	}
+
+	if !job.StartedAt.IsZero() {
+		out.StartedAt = &job.StartedAt
+	}
+
+	if !job.FinishedAt.IsZero() {
+		out.FinishedAt = &job.FinishedAt
+	}
+
+	if job.Status == jobs.StatusDone {
+		out.Result = jobs.ResultPath(job.ID)
+	}
+
+	return out
+}
+
+func writeJob(w http.ResponseWriter, code int, job *jobs.Job) error {
+	return writeJSON(w, code, newJobOutput(job))
+}
+
+// JobInput contains params of job in addition to ScreenshotInput.
+type JobInput struct {
+	// URL to POST signed result of job
+	CallbackURL string `schema:"callback_url"`
+}
+
+func parseCallbackURL(v string) error {
+	if v == "" {
+		return nil
+	}
+
+	u, err := url.Parse(v)
+	if err != nil {
+		return err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return xerrors.Errorf("absolute http(s) url is required")
+	}
+
+	return nil
+}
+
+// NewJobCreateHandler enqueues job with same params as image handler.
+func NewJobCreateHandler(queue *jobs.Queue, auth Auth) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		input, err := parseScreenshotInput(w, r, auth)
+		if err != nil {
+			return err
+		}
+
+		opts, err := input.shotOpts()
+		if err != nil {
+			return err
+		}
+
+		jobInput := &JobInput{}
+
+		decoder := schema.NewDecoder()
+		decoder.IgnoreUnknownKeys(true)
+
+		if err := decoder.Decode(jobInput, r.Form); err != nil {
+			err = xerrors.Errorf("decode form: %w", err)
+			return httpError(err, http.StatusUnprocessableEntity)
+		}
+
+		if err := parseCallbackURL(jobInput.CallbackURL); err != nil {
+			err = xerrors.Errorf("parse callback url: %w", err)
+			return httpError(err, http.StatusUnprocessableEntity)
+		}
+
+		job, err := queue.Enqueue(ctx, input.URL, opts, jobInput.CallbackURL)
+		if xerrors.Is(err, jobs.ErrQueueFull) {
+			return httpError(err, http.StatusServiceUnavailable)
+		} else if err != nil {
+			return xerrors.Errorf("enqueue job: %w", err)
+		}
+
+		return writeJob(w, http.StatusAccepted, job)
+	})
+}
+
+// NewJobHandler returns status of job.
+// Job id is random, so knowledge of it is enough to access job.
+func NewJobHandler(queue *jobs.Queue) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		job, err := queue.Get(ctx, chi.URLParam(r, "id"))
+		if xerrors.Is(err, jobs.ErrJobNotFound) {
+			return httpError(err, http.StatusNotFound)
+		} else if err != nil {
+			return xerrors.Errorf("get job: %w", err)
+		}
+
+		return writeJob(w, http.StatusOK, job)
+	})
+}
+
+// NewJobResultHandler returns image rendered by job.
+func NewJobResultHandler(queue *jobs.Queue) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		job, body, err := queue.Result(ctx, chi.URLParam(r, "id"))
+		switch {
+		case xerrors.Is(err, jobs.ErrJobNotFound):
+			return httpError(err, http.StatusNotFound)
+		case xerrors.Is(err, jobs.ErrJobNotDone):
+			err = xerrors.Errorf("job is %s: %w", job.Status, err)
+			return httpError(err, http.StatusConflict)
+		case xerrors.Is(err, jobs.ErrResultNotFound):
+			return httpError(err, http.StatusNotFound)
+		case err != nil:
+			return xerrors.Errorf("get job result: %w", err)
+		}
+
+		w.Header().Set("Content-Type", job.Opts.Render.Format.ContentType())
+
+		if _, err := io.Copy(w, body); err != nil {
+			return xerrors.Errorf("copy output: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/xerrors"
+
+	"github.com/bots-house/webshot/internal/scheduler"
+)
+
+// maxScheduleBodySize limits size of schedule, HTML is included.
+const maxScheduleBodySize = 5 << 20
+
+func allowAdmin(r *http.Request, auth Auth) error {
+	if err := auth.Allow(r.Context(), r); err != nil {
+		err = xerrors.Errorf("unathorized: %w", err)
+		return httpError(err, http.StatusUnauthorized)
+	}
+
+	return nil
+}
+
+// NewSchedulesHandler returns list of schedules.
+func NewSchedulesHandler(sched *scheduler.Scheduler, auth Auth) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, r *http.Request) error {
+		if err := allowAdmin(r, auth); err != nil {
+			return err
+		}
+
+		return writeJSON(w, http.StatusOK, sched.List())
+	})
+}
+
+// NewSchedulePutHandler creates or replaces schedule with id from path.
+func NewSchedulePutHandler(sched *scheduler.Scheduler, auth Auth) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		if err := allowAdmin(r, auth); err != nil {
+			return err
+		}
+
+		schedule := &scheduler.Schedule{}
+
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxScheduleBodySize))
+		decoder.UseNumber()
+
+		if err := decoder.Decode(schedule); err != nil {
+			err = xerrors.Errorf("decode body: %w", err)
+			return httpError(err, http.StatusBadRequest)
+		}
+
+		schedule.ID = chi.URLParam(r, "id")
+		schedule.LastRun = nil
+
+		err := sched.Put(ctx, schedule)
+		if xerrors.Is(err, scheduler.ErrInvalidSchedule) {
+			return httpError(err, http.StatusUnprocessableEntity)
+		} else if err != nil {
+			return xerrors.Errorf("put schedule: %w", err)
+		}
+
+		return writeJSON(w, http.StatusOK, schedule)
+	})
+}
+
+// NewScheduleDeleteHandler removes schedule with id from path.
+func NewScheduleDeleteHandler(sched *scheduler.Scheduler, auth Auth) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		if err := allowAdmin(r, auth); err != nil {
+			return err
+		}
+
+		err := sched.Delete(ctx, chi.URLParam(r, "id"))
+		if xerrors.Is(err, scheduler.ErrScheduleNotFound) {
+			return httpError(err, http.StatusNotFound)
+		} else if err != nil {
+			return xerrors.Errorf("delete schedule: %w", err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+		return nil
+	})
+}
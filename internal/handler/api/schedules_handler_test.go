@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bots-house/webshot/internal/scheduler"
+)
+
+func TestSchedulePutHandler(t *testing.T) {
+	sched, err := scheduler.New(context.Background(), nil, ParseShotParams, scheduler.Opts{})
+	if err != nil {
+		t.Fatalf("new scheduler: %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Method(http.MethodPut, "/admin/schedules/{id}", NewSchedulePutHandler(sched, NewAuthToken("secret")))
+
+	for _, test := range []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"array param", `{"cron": "@daily", "url": "https://example.com", "params": {"hide_selector": [".a", ".b"]}}`, http.StatusOK},
+		{"large integer param", `{"cron": "@daily", "url": "https://example.com", "params": {"ttl": 2592000, "width": 1200}}`, http.StatusOK},
+		{"invalid param", `{"cron": "@daily", "url": "https://example.com", "params": {"ttl": "month"}}`, http.StatusUnprocessableEntity},
+		{"invalid cron", `{"cron": "daily", "url": "https://example.com"}`, http.StatusUnprocessableEntity},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/admin/schedules/a", strings.NewReader(test.body))
+		req.Header.Set("Authorization", "Bearer secret")
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%s: status is %d, expected %d: %s", test.name, rec.Code, test.status, rec.Body.String())
+		}
+	}
+
+	list := sched.List()
+	if len(list) != 1 {
+		t.Fatalf("%d schedules are saved, expected 1", len(list))
+	}
+
+	opts, err := ParseShotParams(list[0].URL, list[0].Params)
+	if err != nil {
+		t.Fatalf("parse params: %v", err)
+	}
+
+	if opts.Cache.TTL != 30*24*time.Hour || opts.Render.Width != 1200 {
+		t.Errorf("ttl is %v and width is %d, expected 720h and 1200", opts.Cache.TTL, opts.Render.Width)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/schema"
+	"golang.org/x/xerrors"
+
+	"github.com/bots-house/webshot/internal"
+	"github.com/bots-house/webshot/internal/renderer"
+	"github.com/bots-house/webshot/internal/service"
+	"github.com/bots-house/webshot/internal/templates"
+)
+
+// TemplateInput contains reserved params of template request.
+// All query params, except signature, are passed to template as data.
+type TemplateInput struct {
+	Scale   float64 `schema:"scale"`
+	Quality int     `schema:"quality"`
+	Timeout int     `schema:"timeout"`
+
+	Fresh    bool `schema:"fresh"`
+	TTL      int  `schema:"ttl"`
+	StaleTTL *int `schema:"stale_ttl"`
+}
+
+func NewTemplateHandler(srv *service.Service, auth Auth, signParam string) http.HandlerFunc {
+	return handleError(func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		name := chi.URLParam(r, "name")
+
+		format, err := internal.ParseImageType(chi.URLParam(r, "format"))
+		if err != nil {
+			err = xerrors.Errorf("parse format: %w", err)
+			return httpError(err, http.StatusNotFound)
+		}
+
+		qs := r.URL.Query()
+
+		input := &TemplateInput{}
+
+		decoder := schema.NewDecoder()
+		decoder.IgnoreUnknownKeys(true)
+
+		if err := decoder.Decode(input, qs); err != nil {
+			err = xerrors.Errorf("decode query: %w", err)
+			return httpError(err, http.StatusUnprocessableEntity)
+		}
+
+		if auth != nil {
+			if err := auth.Allow(ctx, r); err != nil {
+				err = xerrors.Errorf("unathorized: %w", err)
+				return httpError(err, http.StatusUnauthorized)
+			}
+		}
+
+		data := make(map[string]string, len(qs))
+		for k := range qs {
+			if k != signParam {
+				data[k] = qs.Get(k)
+			}
+		}
+
+		cacheOpts := service.CacheOpts{
+			TTL:   time.Second * time.Duration(input.TTL),
+			Fresh: input.Fresh,
+		}
+
+		if input.StaleTTL != nil {
+			staleTTL := time.Second * time.Duration(*input.StaleTTL)
+			cacheOpts.StaleTTL = &staleTTL
+		}
+
+		output, err := srv.ShotTemplate(ctx, name, data, service.ShotOpts{
+			Render: renderer.Opts{
+				Scale:   input.Scale,
+				Format:  format,
+				Quality: input.Quality,
+				Timeout: time.Millisecond * time.Duration(input.Timeout),
+			},
+			Cache: cacheOpts,
+		})
+
+		if xerrors.Is(err, templates.ErrTemplateNotFound) {
+			return httpError(err, http.StatusNotFound)
+		} else if err != nil {
+			return renderError(err)
+		}
+
+		return writeResult(w, output, format)
+	})
+}
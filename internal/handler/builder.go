@@ -8,6 +8,8 @@ import (
 	"github.com/bots-house/webshot/internal/handler/api"
 	"github.com/bots-house/webshot/internal/handler/middleware"
 	"github.com/bots-house/webshot/internal/handler/web"
+	"github.com/bots-house/webshot/internal/jobs"
+	"github.com/bots-house/webshot/internal/scheduler"
 	sentryhttp "github.com/getsentry/sentry-go/http"
 
 	"github.com/bots-house/webshot/internal/service"
@@ -15,8 +17,20 @@ import (
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 )
 
+// signParam is name of query param with HMAC signature.
+const signParam = "sign"
+
 type Builder struct {
 	Service   *service.Service
+	Jobs      *jobs.Queue
+	Batch     api.BatchOpts
+	Scheduler *scheduler.Scheduler
+
+	// Default response mode of image handler
+	Response api.ResponseMode
+
+	// Auth of admin API, admin API is disabled when nil
+	AdminAuth api.Auth
 	Auth      api.Auth
 	BuildInfo internal.BuildInfo
 	Sentry    bool
@@ -48,12 +62,42 @@ func (builder *Builder) Build() http.Handler {
 
 	router.Mount("/", web.New())
 
+	imageHandler := sentryWrapper.Handle(api.NewImageHandler(builder.Service, builder.Auth, builder.Response))
+
+	router.Method(http.MethodGet, "/image", imageHandler)
+	router.Method(http.MethodPost, "/image", imageHandler)
+
 	router.Method(
 		http.MethodGet,
-		"/image",
-		sentryWrapper.Handle(api.NewImageHandler(builder.Service, builder.Auth)),
+		"/template/{name}.{format}",
+		sentryWrapper.Handle(api.NewTemplateHandler(builder.Service, builder.Auth, signParam)),
+	)
+
+	router.Method(
+		http.MethodPost,
+		"/batch",
+		sentryWrapper.Handle(api.NewBatchHandler(builder.Service, builder.Auth, builder.Batch)),
 	)
 
+	// purge is available only with admin auth, signature of image request can't authorize it
+	if builder.AdminAuth != nil {
+		router.Method(http.MethodDelete, "/cache", sentryWrapper.Handle(api.NewCacheDeleteHandler(builder.Service, builder.AdminAuth)))
+	}
+
+	if builder.Jobs != nil {
+		router.Method(http.MethodPost, "/jobs", sentryWrapper.Handle(api.NewJobCreateHandler(builder.Jobs, builder.Auth)))
+		router.Method(http.MethodGet, "/jobs/{id}", sentryWrapper.Handle(api.NewJobHandler(builder.Jobs)))
+		router.Method(http.MethodGet, "/jobs/{id}/result", sentryWrapper.Handle(api.NewJobResultHandler(builder.Jobs)))
+	}
+
+	if builder.AdminAuth != nil && builder.Scheduler != nil {
+		router.Route("/admin/schedules", func(r chi.Router) {
+			r.Method(http.MethodGet, "/", sentryWrapper.Handle(api.NewSchedulesHandler(builder.Scheduler, builder.AdminAuth)))
+			r.Method(http.MethodPut, "/{id}", sentryWrapper.Handle(api.NewSchedulePutHandler(builder.Scheduler, builder.AdminAuth)))
+			r.Method(http.MethodDelete, "/{id}", sentryWrapper.Handle(api.NewScheduleDeleteHandler(builder.Scheduler, builder.AdminAuth)))
+		})
+	}
+
 	router.Get("/version", api.NewVersionHandler(builder.BuildInfo))
 	router.Get("/health", api.NewHealthHandler(time.Now()))
 
@@ -1,4 +1,17 @@
 document.addEventListener("DOMContentLoaded", () => {
+  let defaultValueOf = (el) => {
+    if (el instanceof HTMLSelectElement) {
+      const option = Array.from(el.options).find((o) => o.defaultSelected);
+      return option ? option.value : el.options[0].value;
+    }
+
+    if (el.type == "checkbox") {
+      return el.defaultChecked ? el.value : null;
+    }
+
+    return el.defaultValue;
+  };
+
   let buildUrl = (formEl) => {
     const formData = new FormData(formEl);
 
@@ -7,8 +20,8 @@ document.addEventListener("DOMContentLoaded", () => {
       if (k == "key") {
         continue;
       }
-      const inputEl = formEl.querySelector(`input[name=${k}]`);
-      if (v != inputEl.defaultValue) {
+      const inputEl = formEl.elements.namedItem(k);
+      if (v != defaultValueOf(inputEl)) {
         url.searchParams.set(k, v);
       }
     }
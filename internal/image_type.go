@@ -10,9 +10,13 @@ type ImageFormat int8
 const (
 	ImageFormatPNG ImageFormat = iota
 	ImageFormatJPEG
+	ImageFormatPDF
+	ImageFormatWebP
 
 	pngFormat  = "png"
 	jpegFormat = "jpeg"
+	pdfFormat  = "pdf"
+	webpFormat = "webp"
 )
 
 func ParseImageType(v string) (ImageFormat, error) {
@@ -21,6 +25,10 @@ func ParseImageType(v string) (ImageFormat, error) {
 		return ImageFormatPNG, nil
 	case jpegFormat:
 		return ImageFormatJPEG, nil
+	case pdfFormat:
+		return ImageFormatPDF, nil
+	case webpFormat:
+		return ImageFormatWebP, nil
 	default:
 		return ImageFormat(-1), fmt.Errorf("unsupported image type: %s", v)
 	}
@@ -37,6 +45,10 @@ func (it ImageFormat) Ext() string {
 		return pngFormat
 	case ImageFormatJPEG:
 		return jpegFormat
+	case ImageFormatPDF:
+		return pdfFormat
+	case ImageFormatWebP:
+		return webpFormat
 	default:
 		return "bin"
 	}
@@ -48,6 +60,10 @@ func (it ImageFormat) ContentType() string {
 		return "image/png"
 	case ImageFormatJPEG:
 		return "image/jpeg"
+	case ImageFormatPDF:
+		return "application/pdf"
+	case ImageFormatWebP:
+		return "image/webp"
 	default:
 		return "application/octet-stream"
 	}
@@ -59,6 +75,10 @@ func (it ImageFormat) String() string {
 		return jpegFormat
 	case ImageFormatPNG:
 		return pngFormat
+	case ImageFormatPDF:
+		return pdfFormat
+	case ImageFormatWebP:
+		return webpFormat
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/bots-house/webshot/internal/service"
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrJobNotFound = xerrors.New("job not found")
+	ErrJobNotDone  = xerrors.New("job is not done")
+	ErrQueueFull   = xerrors.New("job queue is full")
+	ErrQueueClosed = xerrors.New("job queue is closed")
+)
+
+// Status of job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRendering Status = "rendering"
+	StatusDone      Status = "done"
+	StatusFailed    Status = "failed"
+)
+
+// IsFinished reports whether job will not change anymore.
+func (status Status) IsFinished() bool {
+	return status == StatusDone || status == StatusFailed
+}
+
+// Job is screenshot rendered in background.
+type Job struct {
+	ID string
+
+	// URL of target page or base URL of HTML
+	URL string
+
+	Opts service.ShotOpts
+
+	// URL to notify when job is finished, optional
+	CallbackURL string
+
+	Status Status
+
+	// Error of failed job
+	Error string
+
+	// Key and public URL of image in storage, when job is done
+	ResultKey string
+	ResultURL string
+
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Duration returns time spent on render.
+func (job *Job) Duration() time.Duration {
+	if job.StartedAt.IsZero() || job.FinishedAt.IsZero() {
+		return 0
+	}
+
+	return job.FinishedAt.Sub(job.StartedAt)
+}
+
+// newJobID returns random id, it's the only secret to access job.
+func newJobID() (string, error) {
+	buf := make([]byte, 16)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
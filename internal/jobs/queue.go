@@ -0,0 +1,292 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/bots-house/webshot/internal/service"
+	"github.com/bots-house/webshot/internal/storage"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultQueueWorkers = 2
+	defaultQueueSize    = 100
+)
+
+// QueueOpts defines limits of Queue.
+type QueueOpts struct {
+	// Number of concurrent renders. Default is 2.
+	Workers int
+
+	// Max number of queued jobs. Default is 100.
+	Size int
+
+	// Delivers callbacks of jobs. Callbacks are ignored, when nil.
+	Webhook *Webhook
+}
+
+func (opts *QueueOpts) getWorkers() int {
+	if opts.Workers <= 0 {
+		return defaultQueueWorkers
+	}
+
+	return opts.Workers
+}
+
+func (opts *QueueOpts) getSize() int {
+	if opts.Size <= 0 {
+		return defaultQueueSize
+	}
+
+	return opts.Size
+}
+
+// Queue renders jobs by bounded pool of workers.
+type Queue struct {
+	service *service.Service
+	store   Store
+	opts    QueueOpts
+
+	queue chan queuedJob
+}
+
+type queuedJob struct {
+	// ctx contains logger of request which created job
+	ctx context.Context
+	job *Job
+}
+
+func NewQueue(srv *service.Service, store Store, opts QueueOpts) *Queue {
+	return &Queue{
+		service: srv,
+		store:   store,
+		opts:    opts,
+		queue:   make(chan queuedJob, opts.getSize()),
+	}
+}
+
+// Run starts workers and blocks until ctx is done and workers are stopped.
+// Jobs left in queue are marked as failed.
+func (queue *Queue) Run(ctx context.Context) {
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < queue.opts.getWorkers(); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			queue.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	for {
+		select {
+		case item := <-queue.queue:
+			queue.finish(item.ctx, item.job, nil, ErrQueueClosed)
+		default:
+			return
+		}
+	}
+}
+
+// Enqueue adds job to render url with opts.
+// When callbackURL is set, it's notified about result of job.
+func (queue *Queue) Enqueue(
+	ctx context.Context,
+	url string,
+	opts service.ShotOpts,
+	callbackURL string,
+) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, xerrors.Errorf("new job id: %w", err)
+	}
+
+	job := &Job{
+		ID:          id,
+		URL:         url,
+		Opts:        opts,
+		CallbackURL: callbackURL,
+		Status:      StatusQueued,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := queue.store.Add(ctx, job); err != nil {
+		return nil, xerrors.Errorf("add job: %w", err)
+	}
+
+	logger := log.Ctx(ctx).With().Str("job_id", id).Logger()
+
+	// worker updates its own copy, returned job is owned by caller
+	cpy := *job
+
+	select {
+	case queue.queue <- queuedJob{ctx: logger.WithContext(context.Background()), job: &cpy}:
+	default:
+		// client is notified by error, so callback is not delivered
+		job.Status = StatusFailed
+		job.Error = ErrQueueFull.Error()
+		job.FinishedAt = time.Now()
+
+		if err := queue.store.Update(ctx, job); err != nil {
+			logger.Error().Err(err).Msg("update job")
+		}
+
+		return nil, ErrQueueFull
+	}
+
+	logger.Info().Str("url", url).Msg("job queued")
+
+	return job, nil
+}
+
+// Get returns job by id.
+func (queue *Queue) Get(ctx context.Context, id string) (*Job, error) {
+	return queue.store.Get(ctx, id)
+}
+
+// Result returns job and image rendered by it.
+// When result is not kept by store, it's opened from storage by key saved on job.
+func (queue *Queue) Result(ctx context.Context, id string) (*Job, io.Reader, error) {
+	job, err := queue.store.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if job.Status != StatusDone {
+		return job, nil, ErrJobNotDone
+	}
+
+	body, err := queue.store.GetResult(ctx, id)
+	if err == nil {
+		return job, bytes.NewReader(body), nil
+	} else if err != ErrResultNotFound {
+		return nil, nil, xerrors.Errorf("get result: %w", err)
+	}
+
+	if job.ResultKey == "" || queue.service.Storage == nil {
+		return job, nil, ErrResultNotFound
+	}
+
+	// image could be removed from storage by purge or gc
+	r, err := queue.service.Storage.Open(ctx, job.ResultKey)
+	if err == storage.ErrFileNotFound {
+		return job, nil, ErrResultNotFound
+	} else if err != nil {
+		return nil, nil, xerrors.Errorf("open result: %w", err)
+	}
+
+	return job, r, nil
+}
+
+func (queue *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-queue.queue:
+			queue.process(ctx, item)
+		}
+	}
+}
+
+func (queue *Queue) process(ctx context.Context, item queuedJob) {
+	job := item.job
+
+	// cancel job on shutdown, but log with logger of job
+	jobCtx, cancel := context.WithCancel(item.ctx)
+	defer cancel()
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+
+	job.Status = StatusRendering
+	job.StartedAt = time.Now()
+
+	if err := queue.store.Update(jobCtx, job); err != nil {
+		log.Ctx(jobCtx).Error().Err(err).Msg("update job")
+	}
+
+	result, err := queue.service.Shot(jobCtx, job.URL, job.Opts)
+	if err != nil {
+		queue.finish(jobCtx, job, nil, err)
+		return
+	}
+
+	job.ResultKey = result.Key
+	job.ResultURL = result.URL
+
+	var body []byte
+
+	// result in storage of service is enough
+	if queue.service.Storage == nil {
+		body, err = io.ReadAll(result.Body)
+		if err != nil {
+			queue.finish(jobCtx, job, nil, xerrors.Errorf("read result: %w", err))
+			return
+		}
+	}
+
+	queue.finish(jobCtx, job, body, nil)
+}
+
+// notify delivers result of job to its callback url in background.
+func (queue *Queue) notify(ctx context.Context, job *Job) {
+	if job.CallbackURL == "" || queue.opts.Webhook == nil {
+		return
+	}
+
+	// delivery should not be canceled with job
+	ctx = log.Ctx(ctx).WithContext(context.Background())
+	payload := newWebhookPayload(job)
+
+	go func() {
+		if err := queue.opts.Webhook.Deliver(ctx, job.CallbackURL, payload); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("callback_url", job.CallbackURL).Msg("webhook failed")
+		}
+	}()
+}
+
+func (queue *Queue) finish(ctx context.Context, job *Job, body []byte, err error) {
+	job.FinishedAt = time.Now()
+
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusDone
+	}
+
+	if body != nil {
+		if err := queue.store.SetResult(ctx, job.ID, body); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("set job result")
+		}
+	}
+
+	if err := queue.store.Update(ctx, job); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("update job")
+	}
+
+	log.Ctx(ctx).Info().
+		Str("status", string(job.Status)).
+		Str("error", job.Error).
+		Dur("took", job.Duration()).
+		Msg("job finished")
+
+	queue.notify(ctx, job)
+}
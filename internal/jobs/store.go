@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrResultNotFound = xerrors.New("job result not found")
+)
+
+// Store keeps state and results of jobs.
+type Store interface {
+	// Add saves new job
+	Add(ctx context.Context, job *Job) error
+
+	// Update replaces state of existing job
+	Update(ctx context.Context, job *Job) error
+
+	// Get returns job by id or ErrJobNotFound
+	Get(ctx context.Context, id string) (*Job, error)
+
+	// SetResult saves image rendered by job
+	SetResult(ctx context.Context, id string, body []byte) error
+
+	// GetResult returns image rendered by job or ErrResultNotFound,
+	// when result is not kept by store (e.g. it's in storage of service).
+	GetResult(ctx context.Context, id string) ([]byte, error)
+}
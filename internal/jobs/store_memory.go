@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps jobs in memory.
+// Finished jobs are removed after retention period.
+type MemoryStore struct {
+	retention time.Duration
+
+	lock    sync.Mutex
+	jobs    map[string]*Job
+	results map[string][]byte
+}
+
+var _ Store = &MemoryStore{}
+
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{
+		retention: retention,
+		jobs:      make(map[string]*Job),
+		results:   make(map[string][]byte),
+	}
+}
+
+func (store *MemoryStore) Add(ctx context.Context, job *Job) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.cleanup()
+
+	cpy := *job
+	store.jobs[job.ID] = &cpy
+
+	return nil
+}
+
+func (store *MemoryStore) Update(ctx context.Context, job *Job) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if _, ok := store.jobs[job.ID]; !ok {
+		return ErrJobNotFound
+	}
+
+	cpy := *job
+	store.jobs[job.ID] = &cpy
+
+	return nil
+}
+
+func (store *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	job, ok := store.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	cpy := *job
+
+	return &cpy, nil
+}
+
+func (store *MemoryStore) SetResult(ctx context.Context, id string, body []byte) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if _, ok := store.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+
+	store.results[id] = body
+
+	return nil
+}
+
+func (store *MemoryStore) GetResult(ctx context.Context, id string) ([]byte, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	body, ok := store.results[id]
+	if !ok {
+		return nil, ErrResultNotFound
+	}
+
+	return body, nil
+}
+
+// cleanup removes jobs finished before retention period, should be called under lock.
+func (store *MemoryStore) cleanup() {
+	if store.retention <= 0 {
+		return
+	}
+
+	deadline := time.Now().Add(-store.retention)
+
+	for id, job := range store.jobs {
+		if job.Status.IsFinished() && job.FinishedAt.Before(deadline) {
+			delete(store.jobs, id)
+			delete(store.results, id)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultWebhookAttempts = 5
+	defaultWebhookBackoff  = time.Second
+	defaultWebhookTimeout  = time.Second * 10
+
+	// SignatureHeader contains hex encoded HMAC-SHA256 of payload.
+	SignatureHeader = "X-Webshot-Signature"
+)
+
+// Signer signs payload of webhook.
+type Signer interface {
+	Sign(payload []byte) string
+}
+
+// WebhookPayload is sent to callback url, when job is finished.
+type WebhookPayload struct {
+	JobID  string `json:"job_id"`
+	URL    string `json:"url"`
+	Status Status `json:"status"`
+
+	// Key and public URL of image in storage, when job is done
+	ResultKey string `json:"result_key,omitempty"`
+	ResultURL string `json:"result_url,omitempty"`
+
+	// Error of failed job
+	Error string `json:"error,omitempty"`
+
+	// Render duration in milliseconds
+	Duration int64 `json:"duration"`
+}
+
+func newWebhookPayload(job *Job) *WebhookPayload {
+	return &WebhookPayload{
+		JobID:     job.ID,
+		URL:       job.URL,
+		Status:    job.Status,
+		ResultKey: job.ResultKey,
+		ResultURL: job.ResultURL,
+		Error:     job.Error,
+		Duration:  job.Duration().Milliseconds(),
+	}
+}
+
+// ResultPath returns path of job result in API.
+func ResultPath(id string) string {
+	return "/jobs/" + id + "/result"
+}
+
+// Webhook delivers payloads to callback urls.
+// Failed deliveries are retried with exponential backoff.
+type Webhook struct {
+	Client *http.Client
+
+	// Payload is not signed, when nil.
+	Signer Signer
+
+	// Max number of attempts. Default is 5.
+	Attempts int
+
+	// Delay before second attempt, doubled for each next one. Default is 1s.
+	Backoff time.Duration
+}
+
+func (hook *Webhook) getClient() *http.Client {
+	if hook.Client == nil {
+		return &http.Client{Timeout: defaultWebhookTimeout}
+	}
+
+	return hook.Client
+}
+
+func (hook *Webhook) getAttempts() int {
+	if hook.Attempts <= 0 {
+		return defaultWebhookAttempts
+	}
+
+	return hook.Attempts
+}
+
+func (hook *Webhook) getBackoff() time.Duration {
+	if hook.Backoff <= 0 {
+		return defaultWebhookBackoff
+	}
+
+	return hook.Backoff
+}
+
+// Deliver posts payload to url until success or attempts are exhausted.
+func (hook *Webhook) Deliver(ctx context.Context, url string, payload *WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return xerrors.Errorf("marshal payload: %w", err)
+	}
+
+	backoff := hook.getBackoff()
+
+	for attempt := 1; ; attempt++ {
+		started := time.Now()
+
+		retry, err := hook.post(ctx, url, payload.JobID, body)
+
+		ev := log.Ctx(ctx).Info()
+		if err != nil {
+			ev = log.Ctx(ctx).Warn().Err(err)
+		}
+
+		ev.Str("callback_url", url).
+			Int("attempt", attempt).
+			Dur("took", time.Since(started)).
+			Msg("deliver webhook")
+
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= hook.getAttempts() {
+			return xerrors.Errorf("deliver after %d attempts: %w", attempt, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+	}
+}
+
+// post sends payload once and reports whether it's worth to retry on error.
+func (hook *Webhook) post(ctx context.Context, url string, jobID string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, xerrors.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webshot-Job-ID", jobID)
+
+	if hook.Signer != nil {
+		req.Header.Set(SignatureHeader, hook.Signer.Sign(body))
+	}
+
+	res, err := hook.getClient().Do(req)
+	if err != nil {
+		return true, xerrors.Errorf("do request: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return false, nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return true, xerrors.Errorf("unexpected status %d", res.StatusCode)
+	default:
+		return false, xerrors.Errorf("unexpected status %d", res.StatusCode)
+	}
+}
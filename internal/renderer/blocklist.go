@@ -0,0 +1,156 @@
+package renderer
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// Blocklist contains hosts, requests to which are blocked during render.
+// Subdomains of blocked hosts are blocked too.
+type Blocklist struct {
+	hosts map[string]struct{}
+}
+
+// LoadBlocklistFile loads blocklist from file, see ParseBlocklist.
+func LoadBlocklistFile(path string) (*Blocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, xerrors.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	return ParseBlocklist(f)
+}
+
+// ParseBlocklist parses hosts-format (0.0.0.0 example.com),
+// EasyList-style domain rules (||example.com^) and plain domain lists.
+// Comments and rules not related to whole domain are ignored.
+func ParseBlocklist(r io.Reader) (*Blocklist, error) {
+	blocklist := &Blocklist{
+		hosts: make(map[string]struct{}),
+	}
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		for _, host := range parseBlocklistLine(scanner.Text()) {
+			blocklist.hosts[host] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, xerrors.Errorf("scan: %w", err)
+	}
+
+	return blocklist, nil
+}
+
+func parseBlocklistLine(line string) []string {
+	line = strings.TrimSpace(line)
+
+	switch {
+	case line == "", strings.HasPrefix(line, "#"):
+		return nil
+	// EasyList comments, headers and exceptions
+	case strings.HasPrefix(line, "!"), strings.HasPrefix(line, "["), strings.HasPrefix(line, "@@"):
+		return nil
+	case strings.HasPrefix(line, "||"):
+		return parseBlocklistRule(line[2:])
+	}
+
+	fields := strings.Fields(line)
+
+	// comment of hosts file, ## inside of field is element hiding rule of EasyList
+	for i, field := range fields {
+		if strings.HasPrefix(field, "#") {
+			fields = fields[:i]
+			break
+		}
+	}
+
+	// plain domain list
+	if len(fields) == 1 {
+		return normalizeBlocklistHosts(fields)
+	}
+
+	// hosts format, first field is address
+	return normalizeBlocklistHosts(fields[1:])
+}
+
+// parseBlocklistRule parses EasyList rule without leading ||.
+// Only rules blocking whole domain on any page are supported,
+// rules with options (e.g. $third-party, $popup, $domain=) are ignored.
+func parseBlocklistRule(rule string) []string {
+	if strings.Contains(rule, "$") {
+		return nil
+	}
+
+	rule = strings.TrimSuffix(rule, "^")
+
+	if strings.ContainsAny(rule, "/*^|") {
+		return nil
+	}
+
+	return normalizeBlocklistHosts([]string{rule})
+}
+
+func normalizeBlocklistHosts(hosts []string) []string {
+	result := make([]string, 0, len(hosts))
+
+	for _, host := range hosts {
+		host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+		switch host {
+		case "", "localhost", "localhost.localdomain", "local", "broadcasthost", "0.0.0.0":
+			continue
+		}
+
+		if !isBlocklistHost(host) {
+			continue
+		}
+
+		result = append(result, host)
+	}
+
+	return result
+}
+
+// isBlocklistHost reports whether v looks like host, so other rules of lists are ignored.
+func isBlocklistHost(v string) bool {
+	for _, c := range v {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Len returns number of blocked hosts.
+func (blocklist *Blocklist) Len() int {
+	return len(blocklist.hosts)
+}
+
+// Blocked reports whether host or any of its parent domains is blocked.
+func (blocklist *Blocklist) Blocked(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	for host != "" {
+		if _, ok := blocklist.hosts[host]; ok {
+			return true
+		}
+
+		idx := strings.Index(host, ".")
+		if idx < 0 {
+			return false
+		}
+
+		host = host[idx+1:]
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package renderer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBlocklistLine(t *testing.T) {
+	for _, test := range []struct {
+		line  string
+		hosts []string
+	}{
+		// hosts files
+		{"0.0.0.0 ads.example.com", []string{"ads.example.com"}},
+		{"127.0.0.1\tads.example.com tracker.example.com", []string{"ads.example.com", "tracker.example.com"}},
+		{"0.0.0.0 Ads.Example.COM.  # comment", []string{"ads.example.com"}},
+		{"127.0.0.1 localhost", []string{}},
+		{"::1 localhost ip6-localhost", []string{"ip6-localhost"}},
+		{"# 0.0.0.0 ads.example.com", nil},
+		{"", nil},
+
+		// plain domain lists
+		{"ads.example.com", []string{"ads.example.com"}},
+		{"/ads/banner.gif", []string{}},
+
+		// EasyList rules
+		{"||ads.example.com^", []string{"ads.example.com"}},
+		{"||ads.example.com", []string{"ads.example.com"}},
+		{"||ads.example.com/banner^", nil},
+		{"||ads.*.com^", nil},
+		{"! comment", nil},
+		{"[Adblock Plus 2.0]", nil},
+		{"@@||ads.example.com^", nil},
+		{"example.com##.banner", []string{}},
+		{"example.com#@#.banner", []string{}},
+
+		// rules with options are not applied to whole domain
+		{"||youtube.com^$popup", nil},
+		{"||cdn.example.com^$domain=foo.com", nil},
+		{"||tracker.example.com^$third-party", nil},
+	} {
+		hosts := parseBlocklistLine(test.line)
+
+		if !reflect.DeepEqual(hosts, test.hosts) {
+			t.Errorf("parseBlocklistLine(%q) is %q, expected %q", test.line, hosts, test.hosts)
+		}
+	}
+}
+
+func TestBlocklistBlocked(t *testing.T) {
+	blocklist, err := ParseBlocklist(strings.NewReader(`
+! EasyList
+||ads.example.com^
+||youtube.com^$popup
+0.0.0.0 tracker.net
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if blocklist.Len() != 2 {
+		t.Errorf("%d hosts are blocked, expected 2", blocklist.Len())
+	}
+
+	for host, blocked := range map[string]bool{
+		"ads.example.com":      true,
+		"cdn.ads.example.com":  true,
+		"ADS.example.com.":     true,
+		"example.com":          false,
+		"notads.example.com":   false,
+		"tracker.net":          true,
+		"www.youtube.com":      false,
+		"tracker.net.evil.com": false,
+	} {
+		if got := blocklist.Blocked(host); got != blocked {
+			t.Errorf("Blocked(%q) is %v, expected %v", host, got, blocked)
+		}
+	}
+}
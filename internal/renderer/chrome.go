@@ -2,13 +2,17 @@ package renderer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"time"
 
 	"github.com/bots-house/webshot/internal"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -20,6 +24,19 @@ type Chrome struct {
 	Debug    bool
 	Args     map[string]string
 	Resolver ChromeResolver
+
+	// Requests to hosts from blocklist are blocked during render.
+	Blocklist *Blocklist
+
+	// Max duration of render, timeout of request is capped by it.
+	// Zero means no limit.
+	Timeout time.Duration
+}
+
+// renderStats contains counters of single render, reported in render log.
+type renderStats struct {
+	// Number of blocked requests
+	Blocked int64
 }
 
 func (chrome *Chrome) buildContextOptions() []chromedp.ContextOption {
@@ -52,90 +69,384 @@ func (chrome *Chrome) Render(
 	url string,
 	opts Opts,
 ) (c []byte, err error) {
+	stats := &renderStats{}
+
 	defer func(started time.Time) {
-		var ev *zerolog.Event
+		logRender(ctx, url, &opts, stats, time.Since(started), err)
+	}(time.Now())
 
+	return chrome.withTimeout(ctx, &opts, func(ctx context.Context) ([]byte, error) {
+		ctx, cancel, err := chrome.newAllocator(ctx)
 		if err != nil {
-			ev = log.Ctx(ctx).Error().Err(err)
-		} else {
-			ev = log.Ctx(ctx).Info()
+			return nil, err
 		}
+		defer cancel()
 
-		ev = ev.Str("url", url).
-			Int("width", opts.getWidth()).
-			Int("height", opts.getHeight()).
-			Float64("scale", opts.getScale()).
-			Str("format", opts.Format.String()).
-			Dur("took", time.Since(started)).
-			Dur("delay", opts.Delay).
-			Bool("full_page", opts.FullPage).
-			Bool("scroll_page", opts.ScrollPage)
+		// create context
+		ctx, cancel = chromedp.NewContext(
+			ctx,
+			chrome.buildContextOptions()...,
+		)
+		defer cancel()
 
-		if opts.Clip.IsSet() {
-			ev = ev.
-				Float64("clip_x", *opts.Clip.X).
-				Float64("clip_y", *opts.Clip.Y).
-				Float64("clip_width", *opts.Clip.Width).
-				Float64("clip_height", *opts.Clip.Height)
-		}
+		return chrome.render(ctx, url, opts, stats)
+	})
+}
 
-		ev.Msg("screenshot")
+// getTimeout returns timeout of request capped by max timeout.
+func (chrome *Chrome) getTimeout(opts *Opts) time.Duration {
+	if chrome.Timeout > 0 && (opts.Timeout == 0 || opts.Timeout > chrome.Timeout) {
+		return chrome.Timeout
+	}
 
-	}(time.Now())
+	return opts.Timeout
+}
+
+// withTimeout runs render with deadline and reports its exceeding as ErrRenderTimeout.
+// Cancellation of parent context (e.g. client disconnect) is returned as is.
+func (chrome *Chrome) withTimeout(
+	ctx context.Context,
+	opts *Opts,
+	render func(ctx context.Context) ([]byte, error),
+) ([]byte, error) {
+	timeout := chrome.getTimeout(opts)
+	if timeout == 0 {
+		return render(ctx)
+	}
 
-	var cancel context.CancelFunc
+	renderCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c, err := render(renderCtx)
+	if err != nil && ctx.Err() == nil && renderCtx.Err() == context.DeadlineExceeded {
+		return nil, xerrors.Errorf("render took longer than %s: %w", timeout, ErrRenderTimeout)
+	}
 
+	return c, err
+}
+
+// newAllocator returns allocator of remote browser if resolver is set, otherwise local one.
+func (chrome *Chrome) newAllocator(ctx context.Context) (context.Context, context.CancelFunc, error) {
 	if chrome.Resolver != nil {
 		wsurl, err := chrome.Resolver.BrowserWebSocketURL(ctx)
 		if err != nil {
-			return nil, xerrors.Errorf("resolve remote browser: %w", err)
+			return nil, nil, xerrors.Errorf("resolve remote browser: %w", err)
 		}
 
 		log.Ctx(ctx).Debug().Str("url", wsurl).Msg("use remote browser")
 
-		ctx, cancel = chromedp.NewRemoteAllocator(ctx, wsurl)
-		defer cancel()
+		ctx, cancel := chromedp.NewRemoteAllocator(ctx, wsurl)
+
+		return ctx, cancel, nil
+	}
+
+	ctx, cancel := chrome.newLocalAllocator(ctx)
+
+	log.Ctx(ctx).Debug().Interface("args", chrome.Args).Msg("use embedded browser")
+
+	return ctx, cancel, nil
+}
+
+func logRender(ctx context.Context, url string, opts *Opts, stats *renderStats, took time.Duration, err error) {
+	var ev *zerolog.Event
+
+	if err != nil {
+		ev = log.Ctx(ctx).Error().Err(err)
 	} else {
-		ctx, cancel = chrome.newLocalAllocator(ctx)
-		defer cancel()
+		ev = log.Ctx(ctx).Info()
+	}
 
-		log.Ctx(ctx).Debug().Interface("args", chrome.Args).Msg("use embedded browser")
+	ev = ev.Str("url", url).
+		Int("width", opts.getWidth()).
+		Int("height", opts.getHeight()).
+		Float64("scale", opts.getScale()).
+		Str("format", opts.Format.String()).
+		Dur("took", took).
+		Dur("delay", opts.Delay).
+		Dur("timeout", opts.Timeout).
+		Str("wait_until", string(opts.getWaitUntil())).
+		Bool("full_page", opts.FullPage).
+		Bool("scroll_page", opts.ScrollPage).
+		Bool("js", opts.JS != "").
+		Bool("css", opts.CSS != "").
+		Int64("blocked", stats.Blocked)
+
+	if len(opts.HideSelectors) > 0 {
+		ev = ev.Strs("hide_selectors", opts.HideSelectors)
 	}
 
-	// create context
-	ctx, cancel = chromedp.NewContext(
-		ctx,
-		chrome.buildContextOptions()...,
-	)
-	defer cancel()
+	// values of cookies and headers are secret
+	if len(opts.Cookies) > 0 {
+		ev = ev.Int("cookies", len(opts.Cookies))
+	}
 
-	var actions []chromedp.Action
+	if len(opts.Headers) > 0 {
+		ev = ev.Strs("headers", opts.headerNames())
+	}
 
-	// go to url
-	actions = append(actions, logAction(ctx,
-		"navigate", logFields{
-			"url": url,
-		},
-		chromedp.Navigate(url),
-	))
+	if opts.UserAgent != "" {
+		ev = ev.Str("user_agent", opts.UserAgent)
+	}
+
+	if opts.HTML != "" {
+		ev = ev.
+			Int("html_size", len(opts.HTML)).
+			Str("html_hash", opts.ContentHash())
+	}
+
+	if opts.Device != "" {
+		ev = ev.
+			Str("device", opts.Device).
+			Bool("landscape", opts.Landscape)
+	}
+
+	if opts.hasEmulatedMedia() {
+		ev = ev.
+			Str("color_scheme", string(opts.ColorScheme)).
+			Bool("reduced_motion", opts.ReducedMotion).
+			Str("media", string(opts.Media))
+	}
+
+	if opts.Timezone != "" {
+		ev = ev.Str("timezone", opts.Timezone)
+	}
+
+	if opts.Locale != "" {
+		ev = ev.Str("locale", opts.Locale)
+	}
+
+	if opts.Geolocation.IsSet() {
+		ev = ev.
+			Float64("latitude", *opts.Geolocation.Latitude).
+			Float64("longitude", *opts.Geolocation.Longitude).
+			Float64("accuracy", opts.Geolocation.Accuracy)
+	}
+
+	if opts.Clip.IsSet() {
+		ev = ev.
+			Float64("clip_x", *opts.Clip.X).
+			Float64("clip_y", *opts.Clip.Y).
+			Float64("clip_width", *opts.Clip.Width).
+			Float64("clip_height", *opts.Clip.Height)
+	}
+
+	if opts.WaitForSelector != "" {
+		ev = ev.Str("wait_for_selector", opts.WaitForSelector)
+	}
+
+	if opts.WaitForFunction != "" {
+		ev = ev.Str("wait_for_function", opts.WaitForFunction)
+	}
+
+	if opts.Selector != "" {
+		ev = ev.
+			Str("selector", opts.Selector).
+			Float64("selector_padding", opts.SelectorPadding)
+	}
+
+	if opts.Format == internal.ImageFormatPDF {
+		ev = ev.
+			Float64("paper_width", opts.PDF.getPaperWidth()).
+			Float64("paper_height", opts.PDF.getPaperHeight()).
+			Bool("landscape", opts.PDF.Landscape).
+			Str("page_ranges", opts.PDF.PageRanges)
+	}
+
+	ev.Msg("screenshot")
+}
+
+// render runs actions in tab defined by chromedp context.
+func (chrome *Chrome) render(ctx context.Context, url string, opts Opts, stats *renderStats) ([]byte, error) {
+	blocker := newRequestBlocker(&opts, chrome.Blocklist)
+
+	if blocker != nil {
+		chromedp.ListenTarget(ctx, blocker.listen(ctx))
+
+		defer func() {
+			stats.Blocked = blocker.count()
+		}()
+	}
+
+	var idle *networkIdle
+
+	if opts.getWaitUntil() == WaitUntilNetworkIdle {
+		idle = newNetworkIdle(opts.NetworkIdleInflight)
+		chromedp.ListenTarget(ctx, idle.listen)
+	}
+
+	var actions []chromedp.Action
 
-	// set size and scale
+	// set size, scale and device features before navigation,
+	// so page is loaded as on the emulated device
 	actions = append(actions, logAction(ctx,
-		"emulate viewport",
+		"emulate device",
 
 		logFields{
+			"device": opts.Device,
 			"width":  opts.getWidth(),
 			"height": opts.getHeight(),
 			"scale":  opts.getScale(),
 		},
 
-		chromedp.EmulateViewport(
-			int64(opts.getWidth()),
-			int64(opts.getHeight()),
-			chromedp.EmulateScale(opts.getScale()),
-		),
+		emulateDevice(&opts),
 	))
 
+	if opts.hasEmulatedMedia() {
+		actions = append(actions, logAction(ctx,
+			"emulate media",
+			logFields{
+				"color_scheme":   opts.ColorScheme,
+				"reduced_motion": opts.ReducedMotion,
+				"media":          opts.Media,
+			},
+			emulateMedia(&opts),
+		))
+	}
+
+	if opts.Timezone != "" {
+		actions = append(actions, logAction(ctx,
+			"emulate timezone",
+			logFields{"timezone": opts.Timezone},
+			emulation.SetTimezoneOverride(opts.Timezone),
+		))
+	}
+
+	if opts.Locale != "" {
+		actions = append(actions, logAction(ctx,
+			"emulate locale",
+			logFields{"locale": opts.Locale},
+			emulation.SetLocaleOverride().WithLocale(opts.Locale),
+		))
+	}
+
+	if opts.Geolocation.IsSet() {
+		actions = append(actions, logAction(ctx,
+			"emulate geolocation",
+			logFields{
+				"latitude":  *opts.Geolocation.Latitude,
+				"longitude": *opts.Geolocation.Longitude,
+				"accuracy":  opts.Geolocation.Accuracy,
+			},
+			emulateGeolocation(&opts.Geolocation),
+		))
+	}
+
+	if userAgent := opts.getUserAgent(); userAgent != "" {
+		actions = append(actions, logAction(ctx,
+			"set user agent",
+			nil,
+			setUserAgent(userAgent),
+		))
+	}
+
+	if len(opts.Headers) > 0 {
+		actions = append(actions, logAction(ctx,
+			"set extra headers",
+			logFields{"names": opts.headerNames()},
+			setExtraHeaders(opts.Headers),
+		))
+	}
+
+	if len(opts.Cookies) > 0 {
+		actions = append(actions, logAction(ctx,
+			"set cookies",
+			logFields{"count": len(opts.Cookies)},
+			setCookies(url, opts.Cookies),
+		))
+	}
+
+	if blocker != nil {
+		actions = append(actions, logAction(ctx,
+			"enable request blocking",
+			logFields{"resources": opts.BlockResources},
+			blocker.enable(),
+		))
+	}
+
+	if opts.HTML != "" {
+		// render provided html
+		actions = append(actions, logAction(ctx,
+			"load html", logFields{
+				"base_url":   url,
+				"size":       len(opts.HTML),
+				"wait_until": opts.getWaitUntil(),
+			},
+			withWaitTimeout("load html", opts.getWaitTimeout(), loadHTML(opts.HTML, url, &opts)),
+		))
+	} else {
+		// go to url
+		actions = append(actions, logAction(ctx,
+			"navigate", logFields{
+				"url":        url,
+				"wait_until": opts.getWaitUntil(),
+			},
+			withWaitTimeout("navigate", opts.getWaitTimeout(), navigate(url, &opts)),
+		))
+	}
+
+	if idle != nil {
+		actions = append(actions, logAction(ctx,
+			"wait for network idle",
+			logFields{
+				"time":     opts.getNetworkIdleTime().String(),
+				"inflight": opts.NetworkIdleInflight,
+			},
+			withWaitTimeout(
+				"wait for network idle",
+				opts.getWaitTimeout(),
+				idle.wait(opts.getNetworkIdleTime()),
+			),
+		))
+	}
+
+	if opts.WaitForSelector != "" {
+		actions = append(actions, logAction(ctx,
+			"wait for selector",
+			logFields{"selector": opts.WaitForSelector},
+			withWaitTimeout(
+				"wait for selector",
+				opts.getWaitTimeout(),
+				chromedp.WaitReady(opts.WaitForSelector, chromedp.ByQuery),
+			),
+		))
+	}
+
+	if opts.WaitForFunction != "" {
+		actions = append(actions, logAction(ctx,
+			"wait for function",
+			logFields{"expr": opts.WaitForFunction},
+			withWaitTimeout(
+				"wait for function",
+				opts.getWaitTimeout(),
+				waitForFunction(opts.WaitForFunction),
+			),
+		))
+	}
+
+	if opts.CSS != "" {
+		actions = append(actions, logAction(ctx,
+			"inject css",
+			nil,
+			injectCSS(opts.CSS),
+		))
+	}
+
+	if len(opts.HideSelectors) > 0 {
+		actions = append(actions, logAction(ctx,
+			"hide selectors",
+			logFields{"selectors": opts.HideSelectors},
+			injectCSS(hideSelectorsCSS(opts.HideSelectors)),
+		))
+	}
+
+	if opts.JS != "" {
+		actions = append(actions, logAction(ctx,
+			"evaluate js",
+			nil,
+			evaluateJS(opts.JS),
+		))
+	}
+
 	if opts.ScrollPage {
 		actions = append(actions,
 			logAction(ctx,
@@ -170,13 +481,26 @@ func (chrome *Chrome) Render(
 
 	var res []byte
 
-	if opts.FullPage {
+	switch {
+	case opts.Format == internal.ImageFormatPDF:
+		actions = append(actions, logAction(ctx,
+			"print to pdf",
+			nil,
+			printToPDF(&res, &opts),
+		))
+	case opts.Selector != "":
+		actions = append(actions, logAction(ctx,
+			"element screenshot",
+			logFields{"selector": opts.Selector},
+			captureElement(&res, &opts),
+		))
+	case opts.FullPage:
 		actions = append(actions, logAction(ctx,
 			"full page screenshot",
 			nil,
 			captureScreenshotFull(&res, &opts),
 		))
-	} else {
+	default:
 		actions = append(actions, logAction(ctx,
 			"screenshot",
 			nil,
@@ -233,18 +557,14 @@ func captureScreenshotFull(res *[]byte, opts *Opts) chromedp.Action {
 		}
 
 		// force viewport emulation
-		err = emulation.SetDeviceMetricsOverride(width, height, 1, false).
-			WithScreenOrientation(&emulation.ScreenOrientation{
-				Type:  emulation.OrientationTypePortraitPrimary,
-				Angle: 0,
-			}).
+		err = emulation.SetDeviceMetricsOverride(width, height, 1, opts.getDevice().Mobile).
+			WithScreenOrientation(opts.getScreenOrientation()).
 			Do(ctx)
 		if err != nil {
 			return err
 		}
 		// capture screenshot
-		*res, err = page.CaptureScreenshot().
-			WithQuality(int64(opts.Quality)).
+		*res, err = withScreenshotFormat(page.CaptureScreenshot(), opts).
 			WithClip(&page.Viewport{
 				X:      contentSize.X,
 				Y:      contentSize.Y,
@@ -259,6 +579,85 @@ func captureScreenshotFull(res *[]byte, opts *Opts) chromedp.Action {
 	})
 }
 
+// emulateDevice is like chromedp.EmulateViewport, but also emulates mobile and touch.
+func emulateDevice(opts *Opts) chromedp.Action {
+	device := opts.getDevice()
+
+	return chromedp.Tasks{
+		emulation.SetDeviceMetricsOverride(
+			int64(opts.getWidth()),
+			int64(opts.getHeight()),
+			opts.getScale(),
+			device.Mobile,
+		).WithScreenOrientation(opts.getScreenOrientation()),
+		emulation.SetTouchEmulationEnabled(device.Touch),
+	}
+}
+
+// emulateGeolocation overrides position and grants permission to access it
+// in browser context of current tab.
+func emulateGeolocation(geo *OptsGeolocation) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		info, err := target.GetTargetInfo().Do(ctx)
+		if err != nil {
+			return xerrors.Errorf("get target info: %w", err)
+		}
+
+		// permissions are managed by browser, not by tab
+		executor := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Browser)
+
+		if err := browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation}).
+			WithBrowserContextID(info.BrowserContextID).
+			Do(executor); err != nil {
+			return xerrors.Errorf("grant permissions: %w", err)
+		}
+
+		return emulation.SetGeolocationOverride().
+			WithLatitude(*geo.Latitude).
+			WithLongitude(*geo.Longitude).
+			WithAccuracy(geo.Accuracy).
+			Do(ctx)
+	})
+}
+
+func emulateMedia(opts *Opts) chromedp.Action {
+	var features []*emulation.MediaFeature
+
+	if opts.ColorScheme != "" {
+		features = append(features, &emulation.MediaFeature{
+			Name:  "prefers-color-scheme",
+			Value: string(opts.ColorScheme),
+		})
+	}
+
+	if opts.ReducedMotion {
+		features = append(features, &emulation.MediaFeature{
+			Name:  "prefers-reduced-motion",
+			Value: "reduce",
+		})
+	}
+
+	return emulation.SetEmulatedMedia().
+		WithMedia(string(opts.Media)).
+		WithFeatures(features)
+}
+
+// captureScreenshotFormatWebp is missing in cdproto, but supported by Chrome.
+const captureScreenshotFormatWebp page.CaptureScreenshotFormat = "webp"
+
+func withScreenshotFormat(call *page.CaptureScreenshotParams, opts *Opts) *page.CaptureScreenshotParams {
+	switch opts.Format {
+	case internal.ImageFormatJPEG:
+		call = call.WithFormat(page.CaptureScreenshotFormatJpeg)
+	case internal.ImageFormatPNG:
+		call = call.WithFormat(page.CaptureScreenshotFormatPng)
+	case internal.ImageFormatWebP:
+		call = call.WithFormat(captureScreenshotFormatWebp)
+	}
+
+	return call.WithQuality(int64(opts.Quality))
+}
+
 func captureScreenshot(res *[]byte, opts *Opts) chromedp.Action {
 	if res == nil {
 		panic("res cannot be nil")
@@ -269,14 +668,7 @@ func captureScreenshot(res *[]byte, opts *Opts) chromedp.Action {
 
 		call := page.CaptureScreenshot()
 
-		switch opts.Format {
-		case internal.ImageFormatJPEG:
-			call = call.WithFormat(page.CaptureScreenshotFormatJpeg)
-		case internal.ImageFormatPNG:
-			call = call.WithFormat(page.CaptureScreenshotFormatPng)
-		}
-
-		call = call.WithQuality(int64(opts.Quality))
+		call = withScreenshotFormat(call, opts)
 
 		if opts.Clip.IsSet() {
 			call = call.WithClip(&page.Viewport{
@@ -292,3 +684,124 @@ func captureScreenshot(res *[]byte, opts *Opts) chromedp.Action {
 		return err
 	})
 }
+
+type elementBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// clip returns box with padding, padding is cut at top and left edges of document.
+func (box *elementBox) clip(padding float64) *page.Viewport {
+	x := math.Max(box.X-padding, 0)
+	y := math.Max(box.Y-padding, 0)
+
+	return &page.Viewport{
+		X:      x,
+		Y:      y,
+		Width:  box.X + box.Width + padding - x,
+		Height: box.Y + box.Height + padding - y,
+		Scale:  1.0,
+	}
+}
+
+// elementBoxScript returns bounding box of element relative to document,
+// so elements below the fold are handled too.
+const elementBoxScript = `(() => {
+	const el = document.querySelector(%s);
+	if (!el) {
+		return null;
+	}
+
+	const rect = el.getBoundingClientRect();
+
+	return {
+		x: rect.left + window.scrollX,
+		y: rect.top + window.scrollY,
+		width: rect.width,
+		height: rect.height,
+	};
+})()`
+
+func captureElement(res *[]byte, opts *Opts) chromedp.Action {
+	if res == nil {
+		panic("res cannot be nil")
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		waitCtx, cancel := context.WithTimeout(ctx, opts.getWaitTimeout())
+		defer cancel()
+
+		if err := chromedp.WaitReady(opts.Selector, chromedp.ByQuery).Do(waitCtx); err != nil {
+			if ctx.Err() == nil && waitCtx.Err() == context.DeadlineExceeded {
+				return xerrors.Errorf("wait for '%s': %w", opts.Selector, ErrElementNotFound)
+			}
+
+			return xerrors.Errorf("wait for '%s': %w", opts.Selector, err)
+		}
+
+		selector, err := json.Marshal(opts.Selector)
+		if err != nil {
+			return xerrors.Errorf("marshal selector: %w", err)
+		}
+
+		var box *elementBox
+
+		if err := chromedp.Evaluate(fmt.Sprintf(elementBoxScript, selector), &box).Do(ctx); err != nil {
+			return xerrors.Errorf("get bounding box of '%s': %w", opts.Selector, err)
+		}
+
+		if box == nil || box.Width == 0 || box.Height == 0 {
+			return xerrors.Errorf("'%s' has empty bounding box: %w", opts.Selector, ErrElementNotFound)
+		}
+
+		*res, err = withScreenshotFormat(page.CaptureScreenshot(), opts).
+			WithClip(box.clip(opts.SelectorPadding)).
+			WithCaptureBeyondViewport(true).
+			Do(ctx)
+
+		return err
+	})
+}
+
+func printToPDF(res *[]byte, opts *Opts) chromedp.Action {
+	if res == nil {
+		panic("res cannot be nil")
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+
+		call := page.PrintToPDF().
+			WithPaperWidth(opts.PDF.getPaperWidth()).
+			WithPaperHeight(opts.PDF.getPaperHeight()).
+			WithMarginTop(opts.PDF.getMarginTop()).
+			WithMarginBottom(opts.PDF.getMarginBottom()).
+			WithMarginLeft(opts.PDF.getMarginLeft()).
+			WithMarginRight(opts.PDF.getMarginRight()).
+			WithLandscape(opts.PDF.Landscape).
+			WithPrintBackground(opts.PDF.PrintBackground).
+			WithPageRanges(opts.PDF.PageRanges)
+
+		if opts.PDF.hasHeaderFooter() {
+			call = call.
+				WithDisplayHeaderFooter(true).
+				WithHeaderTemplate(pdfTemplateOrEmpty(opts.PDF.HeaderTemplate)).
+				WithFooterTemplate(pdfTemplateOrEmpty(opts.PDF.FooterTemplate))
+		}
+
+		*res, _, err = call.Do(ctx)
+		return err
+	})
+}
+
+// pdfTemplateOrEmpty prevents Chrome from rendering default header or footer,
+// when only one of them is provided.
+func pdfTemplateOrEmpty(tmpl string) string {
+	if tmpl == "" {
+		return "<span></span>"
+	}
+
+	return tmpl
+}
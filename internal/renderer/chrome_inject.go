@@ -0,0 +1,69 @@
+package renderer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/xerrors"
+)
+
+// injectStyleScript appends style element with provided CSS to document.
+const injectStyleScript = `(() => {
+	const style = document.createElement("style");
+	style.textContent = %s;
+	(document.head || document.documentElement).appendChild(style);
+})()`
+
+// injectCSS adds CSS to the page.
+func injectCSS(css string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		text, err := json.Marshal(css)
+		if err != nil {
+			return xerrors.Errorf("marshal css: %w", err)
+		}
+
+		if err := chromedp.Evaluate(fmt.Sprintf(injectStyleScript, text), nil).Do(ctx); err != nil {
+			return xerrors.Errorf("inject css: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// hideSelectorsCSS returns CSS which hides elements matched by selectors.
+// Each selector gets own rule, so invalid one does not break others.
+func hideSelectorsCSS(selectors []string) string {
+	rules := make([]string, len(selectors))
+
+	for i, selector := range selectors {
+		rules[i] = fmt.Sprintf("%s { display: none !important; }", selector)
+	}
+
+	return strings.Join(rules, "\n")
+}
+
+// evaluateJS runs user provided script and awaits returned promise.
+func evaluateJS(js string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		err := chromedp.Evaluate(js, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}).Do(ctx)
+
+		return wrapScriptErr(err)
+	})
+}
+
+// wrapScriptErr marks JS exceptions with ErrScriptFailed.
+func wrapScriptErr(err error) error {
+	var exception *runtime.ExceptionDetails
+
+	if xerrors.As(err, &exception) {
+		return xerrors.Errorf("%s: %w", exception.Error(), ErrScriptFailed)
+	}
+
+	return err
+}
@@ -0,0 +1,160 @@
+package renderer
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+)
+
+// setCookies sets cookies for target page.
+// Cookies without domain are bound to target url.
+func setCookies(url string, cookies []OptsCookie) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		params := make([]*network.CookieParam, len(cookies))
+
+		for i, cookie := range cookies {
+			param := &network.CookieParam{
+				Name:   cookie.Name,
+				Value:  cookie.Value,
+				Domain: cookie.Domain,
+				Path:   cookie.Path,
+			}
+
+			if param.Domain == "" {
+				param.URL = url
+			} else if param.Path == "" {
+				param.Path = "/"
+			}
+
+			params[i] = param
+		}
+
+		if err := network.SetCookies(params).Do(ctx); err != nil {
+			return xerrors.Errorf("set cookies: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// setExtraHeaders sets headers sent with every request of page.
+func setExtraHeaders(headers map[string]string) chromedp.Action {
+	values := make(network.Headers, len(headers))
+
+	for k, v := range headers {
+		values[k] = v
+	}
+
+	return network.SetExtraHTTPHeaders(values)
+}
+
+// setUserAgent overrides browser user agent.
+func setUserAgent(userAgent string) chromedp.Action {
+	return emulation.SetUserAgentOverride(userAgent)
+}
+
+var resourceTypes = map[Resource]network.ResourceType{
+	ResourceImage:      network.ResourceTypeImage,
+	ResourceMedia:      network.ResourceTypeMedia,
+	ResourceFont:       network.ResourceTypeFont,
+	ResourceScript:     network.ResourceTypeScript,
+	ResourceStylesheet: network.ResourceTypeStylesheet,
+}
+
+// requestBlocker fails requests of blocked resource types and hosts.
+type requestBlocker struct {
+	types     map[network.ResourceType]struct{}
+	blocklist *Blocklist
+
+	blocked int64
+}
+
+// newRequestBlocker returns nil, when there is nothing to block.
+func newRequestBlocker(opts *Opts, blocklist *Blocklist) *requestBlocker {
+	if len(opts.BlockResources) == 0 && (blocklist == nil || blocklist.Len() == 0) {
+		return nil
+	}
+
+	types := make(map[network.ResourceType]struct{}, len(opts.BlockResources))
+
+	for _, resource := range opts.BlockResources {
+		types[resourceTypes[resource]] = struct{}{}
+	}
+
+	return &requestBlocker{
+		types:     types,
+		blocklist: blocklist,
+	}
+}
+
+func (blocker *requestBlocker) isBlocked(ev *fetch.EventRequestPaused, mainFrameID cdp.FrameID) bool {
+	// page itself is never blocked
+	if ev.ResourceType == network.ResourceTypeDocument && ev.FrameID == mainFrameID {
+		return false
+	}
+
+	if _, ok := blocker.types[ev.ResourceType]; ok {
+		return true
+	}
+
+	if blocker.blocklist != nil {
+		u, err := url.Parse(ev.Request.URL)
+		if err == nil && blocker.blocklist.Blocked(u.Hostname()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listen should be registered via chromedp.ListenTarget before navigation.
+func (blocker *requestBlocker) listen(ctx context.Context) func(ev interface{}) {
+	return func(ev interface{}) {
+		paused, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+
+		// listener should not block, so request is handled in background
+		go func() {
+			tab := chromedp.FromContext(ctx).Target
+			executor := cdp.WithExecutor(ctx, tab)
+
+			// id of main frame is equal to id of page target
+			mainFrameID := cdp.FrameID(tab.TargetID)
+
+			var err error
+
+			if blocker.isBlocked(paused, mainFrameID) {
+				atomic.AddInt64(&blocker.blocked, 1)
+				err = fetch.FailRequest(paused.RequestID, network.ErrorReasonBlockedByClient).Do(executor)
+			} else {
+				err = fetch.ContinueRequest(paused.RequestID).Do(executor)
+			}
+
+			if err != nil && ctx.Err() == nil {
+				log.Ctx(ctx).Debug().Err(err).Str("url", paused.Request.URL).Msg("handle paused request")
+			}
+		}()
+	}
+}
+
+// enable starts interception of all requests.
+func (blocker *requestBlocker) enable() chromedp.Action {
+	return fetch.Enable().WithPatterns([]*fetch.RequestPattern{
+		{URLPattern: "*"},
+	})
+}
+
+// count returns number of blocked requests.
+func (blocker *requestBlocker) count() int64 {
+	return atomic.LoadInt64(&blocker.blocked)
+}
@@ -0,0 +1,400 @@
+package renderer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrPoolClosed = xerrors.New("browser pool is closed")
+)
+
+const (
+	defaultPoolSize    = 1
+	defaultPoolMaxTabs = 4
+
+	poolDisposeTimeout = time.Second * 5
+)
+
+// ChromePoolOpts defines limits of ChromePool.
+type ChromePoolOpts struct {
+	// Number of browsers in pool. Default is 1.
+	Size int
+
+	// Max number of concurrent tabs per browser. Default is 4.
+	MaxTabs int
+
+	// Browser is recycled after this number of renders. Zero means never.
+	MaxRenders int
+}
+
+func (opts *ChromePoolOpts) getSize() int {
+	if opts.Size <= 0 {
+		return defaultPoolSize
+	}
+
+	return opts.Size
+}
+
+func (opts *ChromePoolOpts) getMaxTabs() int {
+	if opts.MaxTabs <= 0 {
+		return defaultPoolMaxTabs
+	}
+
+	return opts.MaxTabs
+}
+
+// ChromePool renders pages in long-lived browsers.
+// Each render gets own incognito browser context and tab.
+type ChromePool struct {
+	chrome *Chrome
+	opts   ChromePoolOpts
+
+	// slots limits total number of concurrent tabs
+	slots chan struct{}
+
+	lock     sync.Mutex
+	browsers []*pooledBrowser
+	closed   bool
+	running  sync.WaitGroup
+}
+
+type pooledBrowser struct {
+	id int
+
+	// ready is closed when browser is started or failed to start
+	ready chan struct{}
+	err   error
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	active  int
+	renders int
+	retired bool
+}
+
+// isAlive reports whether browser connection is not lost.
+func (browser *pooledBrowser) isAlive() bool {
+	if browser.ctx.Err() != nil {
+		return false
+	}
+
+	select {
+	case <-chromedp.FromContext(browser.ctx).Browser.LostConnection:
+		return false
+	default:
+		return true
+	}
+}
+
+// NewChromePool creates pool of browsers configured by chrome.
+// Browsers are started lazily and closed when ctx is done.
+func NewChromePool(ctx context.Context, chrome *Chrome, opts ChromePoolOpts) *ChromePool {
+	pool := &ChromePool{
+		chrome:   chrome,
+		opts:     opts,
+		slots:    make(chan struct{}, opts.getSize()*opts.getMaxTabs()),
+		browsers: make([]*pooledBrowser, opts.getSize()),
+	}
+
+	go func() {
+		<-ctx.Done()
+		pool.shutdown(ctx)
+	}()
+
+	return pool
+}
+
+func (pool *ChromePool) Render(
+	ctx context.Context,
+	url string,
+	opts Opts,
+) (c []byte, err error) {
+	stats := &renderStats{}
+
+	defer func(started time.Time) {
+		logRender(ctx, url, &opts, stats, time.Since(started), err)
+	}(time.Now())
+
+	// waiting for free tab counts as part of render
+	return pool.chrome.withTimeout(ctx, &opts, func(ctx context.Context) ([]byte, error) {
+		browser, err := pool.acquire(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("acquire browser: %w", err)
+		}
+
+		crashed := false
+		defer func() {
+			pool.release(ctx, browser, crashed)
+		}()
+
+		tabCtx, cancel, err := pool.newTab(ctx, browser)
+		if err != nil {
+			crashed = !browser.isAlive()
+			return nil, xerrors.Errorf("new tab: %w", err)
+		}
+		defer cancel()
+
+		c, err := pool.chrome.render(tabCtx, url, opts, stats)
+		if err != nil {
+			crashed = !browser.isAlive()
+			return nil, err
+		}
+
+		return c, nil
+	})
+}
+
+// Close stops accepting new renders and waits until all browsers are closed.
+func (pool *ChromePool) Close() error {
+	pool.shutdown(context.Background())
+	pool.running.Wait()
+
+	return nil
+}
+
+func (pool *ChromePool) acquire(ctx context.Context) (*pooledBrowser, error) {
+	select {
+	case pool.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	browser, err := pool.pick(ctx)
+	if err != nil {
+		<-pool.slots
+		return nil, err
+	}
+
+	select {
+	case <-browser.ready:
+	case <-ctx.Done():
+		pool.release(ctx, browser, false)
+		return nil, ctx.Err()
+	}
+
+	if browser.err != nil {
+		pool.release(ctx, browser, true)
+		return nil, browser.err
+	}
+
+	return browser, nil
+}
+
+// pick returns least loaded browser, starting new one when slot is empty.
+// Holding of pool slot guarantees that picked browser has free tab.
+func (pool *ChromePool) pick(ctx context.Context) (*pooledBrowser, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if pool.closed {
+		return nil, ErrPoolClosed
+	}
+
+	idx := 0
+	for i, browser := range pool.browsers {
+		if browser == nil {
+			idx = i
+			break
+		}
+
+		if browser.active < pool.browsers[idx].active {
+			idx = i
+		}
+	}
+
+	browser := pool.browsers[idx]
+	if browser == nil {
+		browser = pool.spawn(ctx, idx)
+		pool.browsers[idx] = browser
+	}
+
+	browser.active++
+	browser.renders++
+
+	if pool.opts.MaxRenders > 0 && browser.renders >= pool.opts.MaxRenders {
+		log.Ctx(ctx).Debug().
+			Int("browser", browser.id).
+			Int("renders", browser.renders).
+			Msg("browser reached max renders, recycle")
+
+		pool.retire(browser)
+	}
+
+	return browser, nil
+}
+
+// spawn starts browser in background, should be called under lock.
+func (pool *ChromePool) spawn(ctx context.Context, id int) *pooledBrowser {
+	browser := &pooledBrowser{
+		id:    id,
+		ready: make(chan struct{}),
+	}
+
+	// browser should outlive request, so only logger is inherited
+	browserCtx := log.Ctx(ctx).WithContext(context.Background())
+
+	pool.running.Add(1)
+
+	go func() {
+		defer close(browser.ready)
+
+		started := time.Now()
+
+		allocCtx, allocCancel, err := pool.chrome.newAllocator(browserCtx)
+		if err != nil {
+			browser.err = xerrors.Errorf("new allocator: %w", err)
+			return
+		}
+
+		ctx, cancel := chromedp.NewContext(allocCtx, pool.chrome.buildContextOptions()...)
+
+		browser.ctx = ctx
+		browser.cancel = func() {
+			cancel()
+			allocCancel()
+		}
+
+		if err := chromedp.Run(ctx); err != nil {
+			browser.err = xerrors.Errorf("start browser: %w", err)
+			return
+		}
+
+		log.Ctx(ctx).Info().
+			Int("browser", id).
+			Dur("took", time.Since(started)).
+			Msg("browser started")
+	}()
+
+	return browser
+}
+
+// retire removes browser from pool, so it will be closed after last render.
+// Should be called under lock.
+func (pool *ChromePool) retire(browser *pooledBrowser) {
+	if browser.retired {
+		return
+	}
+
+	browser.retired = true
+
+	if pool.browsers[browser.id] == browser {
+		pool.browsers[browser.id] = nil
+	}
+}
+
+func (pool *ChromePool) release(ctx context.Context, browser *pooledBrowser, crashed bool) {
+	pool.lock.Lock()
+
+	browser.active--
+
+	if crashed {
+		log.Ctx(ctx).Warn().Int("browser", browser.id).Msg("browser crashed, recycle")
+		pool.retire(browser)
+	}
+
+	closeBrowser := browser.retired && browser.active == 0
+
+	pool.lock.Unlock()
+
+	<-pool.slots
+
+	if closeBrowser {
+		go pool.close(browser)
+	}
+}
+
+func (pool *ChromePool) close(browser *pooledBrowser) {
+	defer pool.running.Done()
+
+	<-browser.ready
+
+	if browser.cancel != nil {
+		browser.cancel()
+	}
+}
+
+func (pool *ChromePool) shutdown(ctx context.Context) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if pool.closed {
+		return
+	}
+
+	pool.closed = true
+
+	log.Ctx(ctx).Info().Msg("shutdown browser pool")
+
+	for _, browser := range pool.browsers {
+		if browser == nil {
+			continue
+		}
+
+		pool.retire(browser)
+
+		if browser.active == 0 {
+			go pool.close(browser)
+		}
+	}
+}
+
+// newTab creates tab in new incognito browser context.
+func (pool *ChromePool) newTab(ctx context.Context, browser *pooledBrowser) (context.Context, context.CancelFunc, error) {
+	executor := cdp.WithExecutor(ctx, chromedp.FromContext(browser.ctx).Browser)
+
+	browserContextID, err := target.CreateBrowserContext().WithDisposeOnDetach(true).Do(executor)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("create browser context: %w", err)
+	}
+
+	logger := log.Ctx(ctx)
+
+	dispose := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), poolDisposeTimeout)
+		defer cancel()
+
+		executor := cdp.WithExecutor(ctx, chromedp.FromContext(browser.ctx).Browser)
+
+		if err := target.DisposeBrowserContext(browserContextID).Do(executor); err != nil {
+			logger.Warn().Err(err).Msg("dispose browser context")
+		}
+	}
+
+	targetID, err := target.CreateTarget("about:blank").
+		WithBrowserContextID(browserContextID).
+		Do(executor)
+	if err != nil {
+		dispose()
+		return nil, nil, xerrors.Errorf("create target: %w", err)
+	}
+
+	tabCtx, tabCancel := chromedp.NewContext(browser.ctx, chromedp.WithTargetID(targetID))
+
+	// inherit logger and cancellation of request
+	tabCtx = logger.WithContext(tabCtx)
+
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			tabCancel()
+		case <-done:
+		}
+	}()
+
+	return tabCtx, func() {
+		close(done)
+		tabCancel()
+		dispose()
+	}, nil
+}
@@ -0,0 +1,38 @@
+package renderer
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/page"
+)
+
+func TestElementBoxClip(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		box     elementBox
+		padding float64
+		clip    page.Viewport
+	}{
+		{
+			name: "no padding",
+			box:  elementBox{X: 10, Y: 20, Width: 100, Height: 50},
+			clip: page.Viewport{X: 10, Y: 20, Width: 100, Height: 50, Scale: 1},
+		},
+		{
+			name:    "padding",
+			box:     elementBox{X: 10, Y: 20, Width: 100, Height: 50},
+			padding: 5,
+			clip:    page.Viewport{X: 5, Y: 15, Width: 110, Height: 60, Scale: 1},
+		},
+		{
+			name:    "padding is cut at edges of document",
+			box:     elementBox{X: 4, Y: 0, Width: 100, Height: 50},
+			padding: 10,
+			clip:    page.Viewport{X: 0, Y: 0, Width: 114, Height: 60, Scale: 1},
+		},
+	} {
+		if clip := test.box.clip(test.padding); *clip != test.clip {
+			t.Errorf("%s: clip is %+v, expected %+v", test.name, *clip, test.clip)
+		}
+	}
+}
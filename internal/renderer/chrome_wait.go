@@ -0,0 +1,213 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/xerrors"
+)
+
+const waitPollInterval = time.Millisecond * 100
+
+// withWaitTimeout limits action execution time and replaces deadline error with ErrWaitTimeout.
+func withWaitTimeout(name string, timeout time.Duration, action chromedp.Action) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		err := action.Do(waitCtx)
+		if err != nil && ctx.Err() == nil && waitCtx.Err() == context.DeadlineExceeded {
+			return xerrors.Errorf("%s: %w", name, ErrWaitTimeout)
+		}
+
+		return err
+	})
+}
+
+// navigate goes to url and waits for lifecycle event defined by opts.
+// Network idle is waited separately by networkIdle.
+func navigate(url string, opts *Opts) chromedp.Action {
+	if opts.getWaitUntil() != WaitUntilDOMContentLoaded {
+		return chromedp.Navigate(url)
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		lctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		loaded := make(chan struct{})
+
+		var once sync.Once
+
+		chromedp.ListenTarget(lctx, func(ev interface{}) {
+			if _, ok := ev.(*page.EventDomContentEventFired); ok {
+				once.Do(func() { close(loaded) })
+			}
+		})
+
+		_, _, errorText, err := page.Navigate(url).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		if errorText != "" {
+			return xerrors.Errorf("page load error %s", errorText)
+		}
+
+		select {
+		case <-loaded:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// loadHTML renders content in blank page and waits for lifecycle event defined by opts.
+func loadHTML(content string, baseURL string, opts *Opts) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := chromedp.Navigate("about:blank").Do(ctx); err != nil {
+			return xerrors.Errorf("navigate to blank page: %w", err)
+		}
+
+		tree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return xerrors.Errorf("get frame tree: %w", err)
+		}
+
+		if err := page.SetDocumentContent(tree.Frame.ID, withBaseURL(content, baseURL)).Do(ctx); err != nil {
+			return xerrors.Errorf("set document content: %w", err)
+		}
+
+		readyState := "complete"
+		if opts.getWaitUntil() == WaitUntilDOMContentLoaded {
+			readyState = "interactive"
+		}
+
+		return poll(ctx, func() (bool, error) {
+			var state string
+
+			if err := chromedp.Evaluate("document.readyState", &state).Do(ctx); err != nil {
+				return false, xerrors.Errorf("get ready state: %w", err)
+			}
+
+			return state == readyState || state == "complete", nil
+		})
+	})
+}
+
+var htmlBaseAnchor = regexp.MustCompile(`(?i)^(?:\s*<!doctype[^>]*>)?(?:\s*<html(?:\s[^>]*)?>)?(?:\s*<head(?:\s[^>]*)?>)?`)
+
+// withBaseURL inserts base element to content, so relative links are resolved against baseURL.
+func withBaseURL(content string, baseURL string) string {
+	if baseURL == "" {
+		return content
+	}
+
+	base := fmt.Sprintf(`<base href="%s">`, html.EscapeString(baseURL))
+
+	idx := htmlBaseAnchor.FindStringIndex(content)[1]
+
+	return content[:idx] + base + content[idx:]
+}
+
+// networkIdle tracks in-flight requests of target.
+type networkIdle struct {
+	threshold int
+
+	lock      sync.Mutex
+	inflight  map[network.RequestID]struct{}
+	idleSince time.Time
+}
+
+func newNetworkIdle(threshold int) *networkIdle {
+	return &networkIdle{
+		threshold: threshold,
+		inflight:  make(map[network.RequestID]struct{}),
+		idleSince: time.Now(),
+	}
+}
+
+// listen should be registered via chromedp.ListenTarget before navigation.
+func (idle *networkIdle) listen(ev interface{}) {
+	idle.lock.Lock()
+	defer idle.lock.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		idle.inflight[ev.RequestID] = struct{}{}
+	case *network.EventLoadingFinished:
+		delete(idle.inflight, ev.RequestID)
+	case *network.EventLoadingFailed:
+		delete(idle.inflight, ev.RequestID)
+	default:
+		return
+	}
+
+	switch {
+	case len(idle.inflight) > idle.threshold:
+		idle.idleSince = time.Time{}
+	case idle.idleSince.IsZero():
+		idle.idleSince = time.Now()
+	}
+}
+
+func (idle *networkIdle) isIdle(window time.Duration) bool {
+	idle.lock.Lock()
+	defer idle.lock.Unlock()
+
+	return !idle.idleSince.IsZero() && time.Since(idle.idleSince) >= window
+}
+
+// wait blocks until network is idle for window.
+func (idle *networkIdle) wait(window time.Duration) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		return poll(ctx, func() (bool, error) {
+			return idle.isIdle(window), nil
+		})
+	})
+}
+
+// waitForFunction blocks until JS expression becomes truthy.
+func waitForFunction(expr string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		return poll(ctx, func() (bool, error) {
+			var ok bool
+
+			if err := chromedp.Evaluate(fmt.Sprintf("!!(%s)", expr), &ok).Do(ctx); err != nil {
+				return false, xerrors.Errorf("evaluate: %w", wrapScriptErr(err))
+			}
+
+			return ok, nil
+		})
+	})
+}
+
+func poll(ctx context.Context, check func() (bool, error)) error {
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := check()
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
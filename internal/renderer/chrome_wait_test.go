@@ -0,0 +1,40 @@
+package renderer
+
+import "testing"
+
+func TestWithBaseURL(t *testing.T) {
+	const base = `<base href="https://example.com/">`
+
+	for _, test := range []struct {
+		content string
+		baseURL string
+		result  string
+	}{
+		{"<h1>Hi</h1>", "", "<h1>Hi</h1>"},
+		{"<h1>Hi</h1>", "https://example.com/", base + "<h1>Hi</h1>"},
+		{
+			"<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<title>Hi</title></head></html>",
+			"https://example.com/",
+			"<!DOCTYPE html>\n<html lang=\"en\">\n<head>" + base + "\n<title>Hi</title></head></html>",
+		},
+		{
+			"<HTML><BODY>Hi</BODY></HTML>",
+			"https://example.com/",
+			"<HTML>" + base + "<BODY>Hi</BODY></HTML>",
+		},
+		{
+			"<html><header>Hi</header></html>",
+			"https://example.com/",
+			"<html>" + base + "<header>Hi</header></html>",
+		},
+		{
+			"<p>Hi</p>",
+			`https://example.com/?a=1&b="2"`,
+			`<base href="https://example.com/?a=1&amp;b=&#34;2&#34;"><p>Hi</p>`,
+		},
+	} {
+		if result := withBaseURL(test.content, test.baseURL); result != test.result {
+			t.Errorf("withBaseURL(%q, %q) is %q, expected %q", test.content, test.baseURL, result, test.result)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package renderer
+
+import (
+	"sort"
+)
+
+// Device defines emulated device parameters.
+// Width and height are in portrait orientation for mobile devices.
+type Device struct {
+	// Viewport size in CSS pixels
+	Width, Height int
+
+	// Device scale factor
+	Scale float64
+
+	// Emulate mobile device: meta viewport, overlay scrollbars, etc.
+	Mobile bool
+
+	// Emulate touch events
+	Touch bool
+
+	// User agent of device, empty means browser default
+	UserAgent string
+}
+
+const (
+	userAgentIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Mobile/15E148 Safari/604.1"
+	userAgentIPad    = "Mozilla/5.0 (iPad; CPU OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Mobile/15E148 Safari/604.1"
+	userAgentPixel   = "Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.120 Mobile Safari/537.36"
+	userAgentGalaxy  = "Mozilla/5.0 (Linux; Android 11; SM-G981B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.120 Mobile Safari/537.36"
+	userAgentDesktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+)
+
+var devices = map[string]Device{
+	"iphone-se": {
+		Width: 375, Height: 667, Scale: 2,
+		Mobile: true, Touch: true, UserAgent: userAgentIPhone,
+	},
+	"iphone-12": {
+		Width: 390, Height: 844, Scale: 3,
+		Mobile: true, Touch: true, UserAgent: userAgentIPhone,
+	},
+	"iphone-12-pro-max": {
+		Width: 428, Height: 926, Scale: 3,
+		Mobile: true, Touch: true, UserAgent: userAgentIPhone,
+	},
+	"pixel-5": {
+		Width: 393, Height: 851, Scale: 2.75,
+		Mobile: true, Touch: true, UserAgent: userAgentPixel,
+	},
+	"galaxy-s20": {
+		Width: 360, Height: 800, Scale: 3,
+		Mobile: true, Touch: true, UserAgent: userAgentGalaxy,
+	},
+	"ipad": {
+		Width: 810, Height: 1080, Scale: 2,
+		Mobile: true, Touch: true, UserAgent: userAgentIPad,
+	},
+	"ipad-pro": {
+		Width: 1024, Height: 1366, Scale: 2,
+		Mobile: true, Touch: true, UserAgent: userAgentIPad,
+	},
+	"laptop": {
+		Width: 1366, Height: 768, Scale: 1,
+		UserAgent: userAgentDesktop,
+	},
+	"desktop": {
+		Width: 1920, Height: 1080, Scale: 1,
+		UserAgent: userAgentDesktop,
+	},
+}
+
+// LookupDevice returns device preset by name.
+func LookupDevice(name string) (Device, bool) {
+	device, ok := devices[name]
+	return device, ok
+}
+
+// DeviceNames returns sorted names of device presets.
+func DeviceNames() []string {
+	names := make([]string, 0, len(devices))
+
+	for name := range devices {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -5,14 +5,28 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"io"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bots-house/webshot/internal"
+	"github.com/chromedp/cdproto/emulation"
 	"golang.org/x/xerrors"
 )
 
 type Opts struct {
+	// HTML to render instead of page fetched by url.
+	// In this case url is used as base of relative links.
+	HTML string
+
+	// Name of device preset to emulate.
+	// Width, Height and Scale override values of preset.
+	Device string
+
+	// Rotate device to landscape orientation.
+	Landscape bool
+
 	// Viewport width in pixels of the browser render. Default is 1680
 	Width int
 
@@ -31,6 +45,70 @@ type Opts struct {
 	// Delay before take screenshot in seconds
 	Delay time.Duration
 
+	// Page lifecycle event to wait after navigation. Default is load.
+	WaitUntil WaitUntil
+
+	// Quiet window of network idle wait. Default is 500ms.
+	NetworkIdleTime time.Duration
+
+	// Max number of in-flight requests, when network is considered idle.
+	NetworkIdleInflight int
+
+	// CSS selector of element to wait before capture.
+	WaitForSelector string
+
+	// JS expression to wait to become truthy before capture.
+	WaitForFunction string
+
+	// Timeout of each wait condition. Default is 30s.
+	WaitTimeout time.Duration
+
+	// Max duration of whole render, capped by limit of renderer.
+	// Zero means limit of renderer.
+	Timeout time.Duration
+
+	// JS code to evaluate before capture.
+	// Returned promise is awaited.
+	JS string
+
+	// CSS to inject before capture.
+	CSS string
+
+	// CSS selectors of elements to hide before capture.
+	HideSelectors []string
+
+	// Cookies to set before navigation.
+	// Values are secret, so they should not be logged.
+	Cookies []OptsCookie
+
+	// Extra HTTP headers sent with every request of page.
+	// Values are secret, so they should not be logged.
+	Headers map[string]string
+
+	// Override of browser user agent.
+	UserAgent string
+
+	// Emulated prefers-color-scheme media feature.
+	ColorScheme ColorScheme
+
+	// Emulate prefers-reduced-motion media feature.
+	ReducedMotion bool
+
+	// Emulated CSS media type.
+	Media Media
+
+	// IANA timezone id to emulate, e.g. Europe/Berlin.
+	Timezone string
+
+	// ICU locale to emulate, e.g. de_DE.
+	Locale string
+
+	// Emulated geolocation, permission to access it is granted automatically.
+	Geolocation OptsGeolocation
+
+	// Types of resources to block.
+	BlockResources []Resource
+
 	// Capture full page screenshot
 	FullPage bool
 
@@ -40,11 +118,30 @@ type Opts struct {
 	// Clip of viewport.
 	// All fields is required.
 	Clip OptsClip
+
+	// CSS selector of element to capture.
+	// Can't be used with Clip.
+	Selector string
+
+	// Padding around selected element in pixels.
+	SelectorPadding float64
+
+	// PDF print options, used only when Format is PDF.
+	PDF OptsPDF
 }
 
 func (opts Opts) Hash() string {
 	buf := &bytes.Buffer{}
 
+	// options added after release are written only when they differ from defaults,
+	// so hashes of existing screenshots are kept
+	buf.WriteString(opts.ContentHash())
+	if opts.Device != "" {
+		writeHashField(buf, "device", opts.Device)
+	}
+	if opts.Landscape {
+		writeHashField(buf, "landscape", "true")
+	}
 	buf.WriteString(strconv.Itoa(opts.getWidth()))
 	buf.WriteString(strconv.Itoa(opts.getHeight()))
 	buf.WriteString(strconv.FormatFloat(opts.getScale(), 'f', -1, 64))
@@ -54,6 +151,75 @@ func (opts Opts) Hash() string {
 	buf.WriteString(strconv.FormatBool(opts.FullPage))
 	buf.WriteString(strconv.FormatBool(opts.ScrollPage))
 
+	// wait and render timeouts are not included, they do not affect result
+	if waitUntil := opts.getWaitUntil(); waitUntil != WaitUntilLoad {
+		writeHashField(buf, "wait_until", string(waitUntil))
+
+		if waitUntil == WaitUntilNetworkIdle {
+			writeHashField(buf, "wait_idle_time", strconv.FormatInt(opts.getNetworkIdleTime().Milliseconds(), 10))
+			writeHashField(buf, "wait_idle_inflight", strconv.Itoa(opts.NetworkIdleInflight))
+		}
+	}
+	if opts.WaitForSelector != "" {
+		writeHashField(buf, "wait_for_selector", opts.WaitForSelector)
+	}
+	if opts.WaitForFunction != "" {
+		writeHashField(buf, "wait_for_function", opts.WaitForFunction)
+	}
+
+	if opts.JS != "" {
+		writeHashField(buf, "js", opts.JS)
+	}
+	if opts.CSS != "" {
+		writeHashField(buf, "css", opts.CSS)
+	}
+	for _, selector := range opts.HideSelectors {
+		writeHashField(buf, "hide_selector", selector)
+	}
+
+	// values of cookies and headers are secret, so only their hashes are used
+	for _, cookie := range opts.Cookies {
+		writeHashField(buf, "cookie", cookie.Name)
+		buf.WriteString(hashSecret(cookie.Value))
+		buf.WriteString(strconv.Quote(cookie.Domain))
+		buf.WriteString(strconv.Quote(cookie.Path))
+	}
+
+	for _, name := range opts.headerNames() {
+		writeHashField(buf, "header", name)
+		buf.WriteString(hashSecret(opts.Headers[name]))
+	}
+
+	if opts.UserAgent != "" {
+		writeHashField(buf, "user_agent", opts.UserAgent)
+	}
+
+	if opts.ColorScheme != "" {
+		writeHashField(buf, "color_scheme", string(opts.ColorScheme))
+	}
+	if opts.ReducedMotion {
+		writeHashField(buf, "reduced_motion", "true")
+	}
+	if opts.Media != "" {
+		writeHashField(buf, "media", string(opts.Media))
+	}
+
+	if opts.Timezone != "" {
+		writeHashField(buf, "timezone", opts.Timezone)
+	}
+	if opts.Locale != "" {
+		writeHashField(buf, "locale", opts.Locale)
+	}
+	if opts.Geolocation.IsSet() {
+		writeHashField(buf, "geo_latitude", formatHashFloat(*opts.Geolocation.Latitude))
+		writeHashField(buf, "geo_longitude", formatHashFloat(*opts.Geolocation.Longitude))
+		writeHashField(buf, "geo_accuracy", formatHashFloat(opts.Geolocation.Accuracy))
+	}
+
+	for _, resource := range opts.BlockResources {
+		writeHashField(buf, "block_resource", string(resource))
+	}
+
 	if opts.Clip.IsSet() {
 		buf.WriteString(strconv.FormatFloat(*opts.Clip.X, 'f', -1, 64))
 		buf.WriteString(strconv.FormatFloat(*opts.Clip.Y, 'f', -1, 64))
@@ -61,6 +227,25 @@ func (opts Opts) Hash() string {
 		buf.WriteString(strconv.FormatFloat(*opts.Clip.Height, 'f', -1, 64))
 	}
 
+	if opts.Selector != "" {
+		writeHashField(buf, "selector", opts.Selector)
+		writeHashField(buf, "selector_padding", formatHashFloat(opts.SelectorPadding))
+	}
+
+	if opts.Format == internal.ImageFormatPDF {
+		writeHashField(buf, "pdf_paper_width", formatHashFloat(opts.PDF.getPaperWidth()))
+		writeHashField(buf, "pdf_paper_height", formatHashFloat(opts.PDF.getPaperHeight()))
+		writeHashField(buf, "pdf_margin_top", formatHashFloat(opts.PDF.getMarginTop()))
+		writeHashField(buf, "pdf_margin_bottom", formatHashFloat(opts.PDF.getMarginBottom()))
+		writeHashField(buf, "pdf_margin_left", formatHashFloat(opts.PDF.getMarginLeft()))
+		writeHashField(buf, "pdf_margin_right", formatHashFloat(opts.PDF.getMarginRight()))
+		writeHashField(buf, "pdf_landscape", strconv.FormatBool(opts.PDF.Landscape))
+		writeHashField(buf, "pdf_print_background", strconv.FormatBool(opts.PDF.PrintBackground))
+		writeHashField(buf, "pdf_page_ranges", opts.PDF.PageRanges)
+		writeHashField(buf, "pdf_header_template", opts.PDF.HeaderTemplate)
+		writeHashField(buf, "pdf_footer_template", opts.PDF.FooterTemplate)
+	}
+
 	h := sha256.New()
 
 	_, _ = io.Copy(h, buf)
@@ -68,14 +253,192 @@ func (opts Opts) Hash() string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// ContentHash returns hash of HTML or empty string if it's not set.
+func (opts Opts) ContentHash() string {
+	if opts.HTML == "" {
+		return ""
+	}
+
+	h := sha256.Sum256([]byte(opts.HTML))
+
+	return hex.EncodeToString(h[:])
+}
+
+// writeHashField writes named value, so equal values of different fields don't clash.
+func writeHashField(buf *bytes.Buffer, name string, value string) {
+	buf.WriteString(name)
+	buf.WriteString(strconv.Quote(value))
+}
+
+func formatHashFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func hashSecret(v string) string {
+	h := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(h[:])
+}
+
+// headerNames returns sorted names of extra headers.
+func (opts *Opts) headerNames() []string {
+	names := make([]string, 0, len(opts.Headers))
+
+	for name := range opts.Headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
 func (opts *Opts) Validate() error {
+	if opts.Device != "" {
+		if _, ok := LookupDevice(opts.Device); !ok {
+			return xerrors.Errorf("unknown device '%s', available: %s", opts.Device, strings.Join(DeviceNames(), ", "))
+		}
+	}
+
 	if err := opts.Clip.Validate(); err != nil {
 		return xerrors.Errorf("validate clip: %w", err)
 	}
 
+	switch opts.WaitUntil {
+	case "", WaitUntilLoad, WaitUntilDOMContentLoaded, WaitUntilNetworkIdle:
+	default:
+		return xerrors.Errorf("unsupported wait until '%s'", opts.WaitUntil)
+	}
+
+	if opts.NetworkIdleTime < 0 {
+		return xerrors.Errorf("network idle time can't be negative")
+	}
+
+	if opts.NetworkIdleInflight < 0 {
+		return xerrors.Errorf("network idle inflight can't be negative")
+	}
+
+	if opts.WaitTimeout < 0 {
+		return xerrors.Errorf("wait timeout can't be negative")
+	}
+
+	if opts.Timeout < 0 {
+		return xerrors.Errorf("timeout can't be negative")
+	}
+
+	switch opts.ColorScheme {
+	case "", ColorSchemeLight, ColorSchemeDark:
+	default:
+		return xerrors.Errorf("unsupported color scheme '%s'", opts.ColorScheme)
+	}
+
+	switch opts.Media {
+	case "", MediaScreen, MediaPrint:
+	default:
+		return xerrors.Errorf("unsupported media '%s'", opts.Media)
+	}
+
+	if opts.Timezone != "" {
+		// Local is valid for Go, but means nothing for browser
+		if _, err := time.LoadLocation(opts.Timezone); err != nil || opts.Timezone == "Local" {
+			return xerrors.Errorf("unknown timezone '%s'", opts.Timezone)
+		}
+	}
+
+	if err := opts.Geolocation.Validate(); err != nil {
+		return xerrors.Errorf("validate geolocation: %w", err)
+	}
+
+	for _, resource := range opts.BlockResources {
+		if _, ok := resourceTypes[resource]; !ok {
+			return xerrors.Errorf("unsupported resource '%s'", resource)
+		}
+	}
+
+	for _, cookie := range opts.Cookies {
+		if cookie.Name == "" {
+			return xerrors.Errorf("cookie name can't be empty")
+		}
+	}
+
+	for name := range opts.Headers {
+		if name == "" {
+			return xerrors.Errorf("header name can't be empty")
+		}
+	}
+
+	if opts.Selector != "" && opts.Clip.IsSet() {
+		return xerrors.Errorf("selector and clip can't be used together")
+	}
+
+	if opts.SelectorPadding < 0 {
+		return xerrors.Errorf("selector padding can't be negative")
+	}
+
+	if err := opts.PDF.Validate(); err != nil {
+		return xerrors.Errorf("validate pdf: %w", err)
+	}
+
 	return nil
 }
 
+// ColorScheme defines value of prefers-color-scheme media feature.
+type ColorScheme string
+
+const (
+	ColorSchemeLight ColorScheme = "light"
+	ColorSchemeDark  ColorScheme = "dark"
+)
+
+// Media defines emulated CSS media type.
+type Media string
+
+const (
+	MediaScreen Media = "screen"
+	MediaPrint  Media = "print"
+)
+
+// Resource defines type of resource to block.
+type Resource string
+
+const (
+	ResourceImage      Resource = "image"
+	ResourceMedia      Resource = "media"
+	ResourceFont       Resource = "font"
+	ResourceScript     Resource = "script"
+	ResourceStylesheet Resource = "stylesheet"
+)
+
+// hasEmulatedMedia reports whether media type or features should be emulated.
+func (opts *Opts) hasEmulatedMedia() bool {
+	return opts.ColorScheme != "" || opts.ReducedMotion || opts.Media != ""
+}
+
+// OptsCookie defines cookie of target page.
+type OptsCookie struct {
+	Name  string
+	Value string
+
+	// Domain of cookie. Default is host of target url.
+	Domain string
+
+	// Path of cookie. Default is /.
+	Path string
+}
+
+// WaitUntil defines page lifecycle event to wait after navigation.
+type WaitUntil string
+
+const (
+	// WaitUntilLoad waits for load event.
+	WaitUntilLoad WaitUntil = "load"
+
+	// WaitUntilDOMContentLoaded waits for DOMContentLoaded event.
+	WaitUntilDOMContentLoaded WaitUntil = "domcontentloaded"
+
+	// WaitUntilNetworkIdle waits for load event and then for network quiet window.
+	WaitUntilNetworkIdle WaitUntil = "networkidle"
+)
+
 type OptsClip struct {
 	X, Y          *float64
 	Width, Height *float64
@@ -124,15 +487,208 @@ func (optsClip OptsClip) Validate() error {
 	return nil
 }
 
+// OptsGeolocation defines emulated position of device.
+// Latitude and Longitude are required, Accuracy is in meters.
+type OptsGeolocation struct {
+	Latitude, Longitude *float64
+	Accuracy            float64
+}
+
+func (geo *OptsGeolocation) IsSet() bool {
+	return geo.Latitude != nil && geo.Longitude != nil
+}
+
+func (geo OptsGeolocation) Validate() error {
+	if geo.Latitude == nil && geo.Longitude == nil {
+		return nil
+	}
+
+	if geo.Latitude == nil {
+		return xerrors.Errorf("missing field `latitude`")
+	}
+
+	if geo.Longitude == nil {
+		return xerrors.Errorf("missing field `longitude`")
+	}
+
+	if *geo.Latitude < -90 || *geo.Latitude > 90 {
+		return xerrors.Errorf("latitude should be in range [-90, 90]")
+	}
+
+	if *geo.Longitude < -180 || *geo.Longitude > 180 {
+		return xerrors.Errorf("longitude should be in range [-180, 180]")
+	}
+
+	if geo.Accuracy < 0 {
+		return xerrors.Errorf("accuracy can't be negative")
+	}
+
+	return nil
+}
+
+// OptsPDF contains options of page.PrintToPDF.
+// All sizes are in inches.
+type OptsPDF struct {
+	// Paper width. Default is 8.5
+	PaperWidth float64
+
+	// Paper height. Default is 11
+	PaperHeight float64
+
+	// Margins of page. Default is 0.4 (~1cm)
+	MarginTop, MarginBottom *float64
+	MarginLeft, MarginRight *float64
+
+	// Paper orientation
+	Landscape bool
+
+	// Page ranges to print, e.g. '1-5, 8, 11-13'. Empty means all pages.
+	PageRanges string
+
+	// Print background graphics
+	PrintBackground bool
+
+	// HTML templates of header and footer.
+	// Footer and header are displayed when any of them is set.
+	HeaderTemplate string
+	FooterTemplate string
+}
+
+func (optsPDF OptsPDF) Validate() error {
+	if optsPDF.PaperWidth < 0 {
+		return xerrors.Errorf("field `paper_width` can't be negative")
+	}
+
+	if optsPDF.PaperHeight < 0 {
+		return xerrors.Errorf("field `paper_height` can't be negative")
+	}
+
+	margins := []struct {
+		name  string
+		value *float64
+	}{
+		{"margin_top", optsPDF.MarginTop},
+		{"margin_bottom", optsPDF.MarginBottom},
+		{"margin_left", optsPDF.MarginLeft},
+		{"margin_right", optsPDF.MarginRight},
+	}
+
+	for _, margin := range margins {
+		if margin.value != nil && *margin.value < 0 {
+			return xerrors.Errorf("field `%s` can't be negative", margin.name)
+		}
+	}
+
+	return nil
+}
+
+func (optsPDF *OptsPDF) hasHeaderFooter() bool {
+	return optsPDF.HeaderTemplate != "" || optsPDF.FooterTemplate != ""
+}
+
+const (
+	defaultPaperWidth  = 8.5
+	defaultPaperHeight = 11
+	defaultMargin      = 0.4
+)
+
+func (optsPDF *OptsPDF) getPaperWidth() float64 {
+	if optsPDF.PaperWidth == 0 {
+		return defaultPaperWidth
+	}
+
+	return optsPDF.PaperWidth
+}
+
+func (optsPDF *OptsPDF) getPaperHeight() float64 {
+	if optsPDF.PaperHeight == 0 {
+		return defaultPaperHeight
+	}
+
+	return optsPDF.PaperHeight
+}
+
+func getMargin(v *float64) float64 {
+	if v == nil {
+		return defaultMargin
+	}
+
+	return *v
+}
+
+func (optsPDF *OptsPDF) getMarginTop() float64 {
+	return getMargin(optsPDF.MarginTop)
+}
+
+func (optsPDF *OptsPDF) getMarginBottom() float64 {
+	return getMargin(optsPDF.MarginBottom)
+}
+
+func (optsPDF *OptsPDF) getMarginLeft() float64 {
+	return getMargin(optsPDF.MarginLeft)
+}
+
+func (optsPDF *OptsPDF) getMarginRight() float64 {
+	return getMargin(optsPDF.MarginRight)
+}
+
 const (
 	defaultWidth  = 1680
 	defaultHeight = 867
 	defaultScale  = 1
+
+	defaultNetworkIdleTime = time.Millisecond * 500
+	defaultWaitTimeout     = time.Second * 30
 )
 
+func (opts *Opts) getWaitUntil() WaitUntil {
+	if opts.WaitUntil == "" {
+		return WaitUntilLoad
+	}
+
+	return opts.WaitUntil
+}
+
+func (opts *Opts) getNetworkIdleTime() time.Duration {
+	if opts.NetworkIdleTime == 0 {
+		return defaultNetworkIdleTime
+	}
+
+	return opts.NetworkIdleTime
+}
+
+func (opts *Opts) getWaitTimeout() time.Duration {
+	if opts.WaitTimeout == 0 {
+		return defaultWaitTimeout
+	}
+
+	return opts.WaitTimeout
+}
+
+var defaultDevice = Device{
+	Width:  defaultWidth,
+	Height: defaultHeight,
+	Scale:  defaultScale,
+}
+
+// getDevice returns device preset or default desktop viewport.
+// Width and height are swapped in landscape orientation.
+func (opts *Opts) getDevice() Device {
+	device, ok := LookupDevice(opts.Device)
+	if !ok {
+		device = defaultDevice
+	}
+
+	if opts.Landscape && device.Height > device.Width {
+		device.Width, device.Height = device.Height, device.Width
+	}
+
+	return device
+}
+
 func (opts *Opts) getScale() float64 {
 	if opts.Scale == 0 {
-		return defaultScale
+		return opts.getDevice().Scale
 	}
 
 	return opts.Scale
@@ -140,20 +696,44 @@ func (opts *Opts) getScale() float64 {
 
 func (opts *Opts) getWidth() int {
 	if opts.Width == 0 {
-		return defaultWidth
+		return opts.getDevice().Width
 	}
 
 	return opts.Width
 }
 
+// hasWidth reports whether width is set explicitly or by device.
 func (opts *Opts) hasWidth() bool {
-	return opts.Width != 0
+	return opts.Width != 0 || opts.Device != ""
 }
 
 func (opts *Opts) getHeight() int {
 	if opts.Height == 0 {
-		return defaultHeight
+		return opts.getDevice().Height
 	}
 
 	return opts.Height
 }
+
+func (opts *Opts) getScreenOrientation() *emulation.ScreenOrientation {
+	if opts.getWidth() > opts.getHeight() && opts.getDevice().Mobile {
+		return &emulation.ScreenOrientation{
+			Type:  emulation.OrientationTypeLandscapePrimary,
+			Angle: 90,
+		}
+	}
+
+	return &emulation.ScreenOrientation{
+		Type:  emulation.OrientationTypePortraitPrimary,
+		Angle: 0,
+	}
+}
+
+// getUserAgent returns user agent override or user agent of device.
+func (opts *Opts) getUserAgent() string {
+	if opts.UserAgent == "" {
+		return opts.getDevice().UserAgent
+	}
+
+	return opts.UserAgent
+}
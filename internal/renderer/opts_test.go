@@ -0,0 +1,72 @@
+package renderer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bots-house/webshot/internal"
+)
+
+func TestOptsHashDefaults(t *testing.T) {
+	// hashes of options without new fields should not change, they are keys of cached screenshots
+	for _, test := range []struct {
+		opts Opts
+		hash string
+	}{
+		{Opts{}, "375e9defb9a16086cb643211679c3983db7b8752650fc81ce26a6a70f3897c2a"},
+		{
+			Opts{Width: 800, Height: 600, Format: internal.ImageFormatJPEG, Quality: 80, FullPage: true},
+			"8310ac072c31fd472714618affc7afda334fcce459a9d66455b80ea7d14e7ee2",
+		},
+	} {
+		if hash := test.opts.Hash(); hash != test.hash {
+			t.Errorf("hash of %+v is %s, expected %s", test.opts, hash, test.hash)
+		}
+	}
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestOptsHashDiffers(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		a, b Opts
+	}{
+		{
+			name: "pdf paper size",
+			a:    Opts{Format: internal.ImageFormatPDF, PDF: OptsPDF{PaperWidth: 8.5, PaperHeight: 11}},
+			b:    Opts{Format: internal.ImageFormatPDF, PDF: OptsPDF{PaperWidth: 8.51, PaperHeight: 1}},
+		},
+		{
+			name: "pdf margins",
+			a:    Opts{Format: internal.ImageFormatPDF, PDF: OptsPDF{MarginTop: float(1), MarginBottom: float(23)}},
+			b:    Opts{Format: internal.ImageFormatPDF, PDF: OptsPDF{MarginTop: float(12), MarginBottom: float(3)}},
+		},
+		{
+			name: "pdf templates",
+			a:    Opts{Format: internal.ImageFormatPDF, PDF: OptsPDF{PageRanges: "1", HeaderTemplate: "2"}},
+			b:    Opts{Format: internal.ImageFormatPDF, PDF: OptsPDF{PageRanges: "12"}},
+		},
+		{
+			name: "selector padding",
+			a:    Opts{Format: internal.ImageFormatPDF, Selector: "#a", SelectorPadding: 5, PDF: OptsPDF{PaperWidth: 80.5}},
+			b:    Opts{Format: internal.ImageFormatPDF, Selector: "#a", SelectorPadding: 58, PDF: OptsPDF{PaperWidth: 0.5}},
+		},
+		{
+			name: "network idle",
+			a:    Opts{WaitUntil: WaitUntilNetworkIdle, NetworkIdleTime: time.Second, NetworkIdleInflight: 11},
+			b:    Opts{WaitUntil: WaitUntilNetworkIdle, NetworkIdleTime: 10001 * time.Millisecond, NetworkIdleInflight: 1},
+		},
+		{
+			name: "geolocation",
+			a:    Opts{Geolocation: OptsGeolocation{Latitude: float(1), Longitude: float(23)}},
+			b:    Opts{Geolocation: OptsGeolocation{Latitude: float(12), Longitude: float(3)}},
+		},
+	} {
+		if test.a.Hash() == test.b.Hash() {
+			t.Errorf("%s: hashes of %+v and %+v are equal", test.name, test.a, test.b)
+		}
+	}
+}
@@ -2,6 +2,15 @@ package renderer
 
 import (
 	"context"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrElementNotFound = xerrors.New("element not found")
+	ErrWaitTimeout     = xerrors.New("wait timeout")
+	ErrScriptFailed    = xerrors.New("script failed")
+	ErrRenderTimeout   = xerrors.New("render timeout")
 )
 
 type Renderer interface {
@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Cron is parsed cron expression with 5 fields: minute, hour, day of month, month and day of week.
+// Lists (1,2), ranges (1-5), steps (*/15, 1-30/5) and aliases (@hourly, @daily, @weekly, @monthly) are supported.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+
+	// day of month and day of week are matched by OR, when both are restricted,
+	// field starting with * (e.g. */2) is not restricted as in vixie cron
+	domAny, dowAny bool
+}
+
+var cronAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron parses cron expression.
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+
+	if alias, ok := cronAliases[expr]; ok {
+		expr = alias
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, xerrors.Errorf("expected %d fields, got %d", len(cronFields), len(parts))
+	}
+
+	bits := make([]uint64, len(parts))
+
+	for i, part := range parts {
+		v, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, xerrors.Errorf("parse %s: %w", cronFields[i].name, err)
+		}
+
+		bits[i] = v
+	}
+
+	// 7 is sunday too
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Cron{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseCronField(v string, field cronField) (uint64, error) {
+	var bits uint64
+
+	for _, item := range strings.Split(v, ",") {
+		step := 1
+
+		if idx := strings.Index(item, "/"); idx != -1 {
+			var err error
+
+			step, err = strconv.Atoi(item[idx+1:])
+			if err != nil || step <= 0 {
+				return 0, xerrors.Errorf("invalid step '%s'", item[idx+1:])
+			}
+
+			item = item[:idx]
+		}
+
+		from, to := field.min, field.max
+
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			bounds := strings.SplitN(item, "-", 2)
+
+			var err error
+
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, xerrors.Errorf("invalid value '%s'", bounds[0])
+			}
+
+			if to, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, xerrors.Errorf("invalid value '%s'", bounds[1])
+			}
+		default:
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return 0, xerrors.Errorf("invalid value '%s'", item)
+			}
+
+			from = n
+
+			// 5/10 means from 5 to max with step 10
+			if step == 1 {
+				to = n
+			}
+		}
+
+		if from < field.min || to > field.max || from > to {
+			return 0, xerrors.Errorf("value '%s' is out of range %d-%d", item, field.min, field.max)
+		}
+
+		for i := from; i <= to; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func hasBit(bits uint64, i int) bool {
+	return bits&(1<<uint(i)) != 0
+}
+
+func (cron *Cron) matchDay(t time.Time) bool {
+	dom := hasBit(cron.dom, t.Day())
+	dow := hasBit(cron.dow, int(t.Weekday()))
+
+	if !cron.domAny && !cron.dowAny {
+		return dom || dow
+	}
+
+	return dom && dow
+}
+
+// cronMaxYears limits search of next time for expressions like "0 0 31 2 *".
+const cronMaxYears = 5
+
+// Next returns first time matching expression after t.
+// Zero time is returned, when there is no such time.
+func (cron *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	limit := t.AddDate(cronMaxYears, 0, 0)
+
+	for t.Before(limit) {
+		if !hasBit(cron.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !cron.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !hasBit(cron.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !hasBit(cron.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
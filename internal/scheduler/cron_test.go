@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		ok   bool
+	}{
+		{"* * * * *", true},
+		{"*/15 0-6 1,15 * 1-5", true},
+		{"5/10 * * * *", true},
+		{"0 0 * * 7", true},
+		{"@daily", true},
+		{" @hourly ", true},
+		{"", false},
+		{"* * * *", false},
+		{"* * * * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"5-1 * * * *", false},
+		{"*/0 * * * *", false},
+		{"*/x * * * *", false},
+		{"a * * * *", false},
+		{"@every 5m", false},
+	} {
+		_, err := ParseCron(test.expr)
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("ParseCron(%q): error %v, ok expected %v", test.expr, err, test.ok)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2021-07-01 is thursday
+	from := time.Date(2021, 7, 1, 12, 30, 20, 0, time.UTC)
+
+	for _, test := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2021, 7, 1, 12, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, 7, 1, 12, 45, 0, 0, time.UTC)},
+		{"5/10 * * * *", time.Date(2021, 7, 1, 12, 35, 0, 0, time.UTC)},
+		{"30 12 * * *", time.Date(2021, 7, 2, 12, 30, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2021, 7, 1, 18, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2021, 7, 1, 13, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2021, 7, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+
+		// 7 is sunday too
+		{"0 0 * * 7", time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC)},
+
+		// both days are restricted, so they are matched by OR: monday 5th is before 10th
+		{"0 0 10 * 1", time.Date(2021, 7, 5, 0, 0, 0, 0, time.UTC)},
+
+		// friday 2nd is before 3rd
+		{"0 0 3 * 5", time.Date(2021, 7, 2, 0, 0, 0, 0, time.UTC)},
+
+		// day of week is not restricted, so only day of month is matched
+		{"0 0 10 * *", time.Date(2021, 7, 10, 0, 0, 0, 0, time.UTC)},
+
+		// */n is not restricted, so days are matched by AND: odd 3rd is skipped, odd monday 5th is matched
+		{"0 0 */2 * 1", time.Date(2021, 7, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 10 * */1", time.Date(2021, 7, 10, 0, 0, 0, 0, time.UTC)},
+
+		// february 29th
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+
+		// never
+		{"0 0 31 2 *", time.Time{}},
+	} {
+		cron, err := ParseCron(test.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", test.expr, err)
+		}
+
+		if got := cron.Next(from); !got.Equal(test.want) {
+			t.Errorf("Next of %q is %v, expected %v", test.expr, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Schedule defines recurring capture of page.
+type Schedule struct {
+	ID   string `json:"id"`
+	Cron string `json:"cron"`
+
+	// URL of target page or base URL of HTML
+	URL string `json:"url"`
+
+	// Params of screenshot, same as params of image handler
+	Params map[string]interface{} `json:"params,omitempty"`
+
+	// Time of last run, used to catch up missed runs after restart
+	LastRun *time.Time `json:"last_run,omitempty"`
+}
+
+var scheduleIDRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func (schedule *Schedule) Validate() error {
+	if !scheduleIDRegexp.MatchString(schedule.ID) {
+		return xerrors.Errorf("id should contain only letters, digits, '-' and '_'")
+	}
+
+	if _, err := ParseCron(schedule.Cron); err != nil {
+		return xerrors.Errorf("parse cron: %w", err)
+	}
+
+	return nil
+}
+
+// scheduleFile is format of file with schedules.
+type scheduleFile struct {
+	Schedules []*Schedule `json:"schedules"`
+}
+
+// loadFile reads schedules from file, missing file means no schedules.
+func loadFile(path string) ([]*Schedule, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, xerrors.Errorf("read file: %w", err)
+	}
+
+	file := &scheduleFile{}
+
+	// numbers of params are kept as is, float64 would format large ones with exponent
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(file); err != nil {
+		return nil, xerrors.Errorf("unmarshal: %w", err)
+	}
+
+	return file.Schedules, nil
+}
+
+// saveFile writes schedules to temporary file and renames it,
+// so file is never left half-written.
+func saveFile(path string, schedules []*Schedule) error {
+	content, err := json.MarshalIndent(&scheduleFile{Schedules: schedules}, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("marshal: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return xerrors.Errorf("create temp file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return xerrors.Errorf("write temp file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return xerrors.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return xerrors.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
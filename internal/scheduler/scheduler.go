@@ -0,0 +1,364 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bots-house/webshot/internal/service"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrScheduleNotFound = xerrors.New("schedule not found")
+	ErrInvalidSchedule  = xerrors.New("invalid schedule")
+)
+
+// CatchUp defines what to do with runs missed during downtime.
+type CatchUp string
+
+const (
+	// CatchUpSkip skips missed runs.
+	CatchUpSkip CatchUp = "skip"
+
+	// CatchUpOnce runs schedule once on start, if any run is missed.
+	CatchUpOnce CatchUp = "once"
+)
+
+const (
+	defaultMaxConcurrent = 2
+
+	// maxSleep limits sleep between checks, so clock changes are noticed
+	maxSleep = time.Minute
+)
+
+// ParseFunc converts params of schedule to options of screenshot.
+type ParseFunc func(url string, params map[string]interface{}) (service.ShotOpts, error)
+
+// Opts defines behaviour of Scheduler.
+type Opts struct {
+	// File with schedules, changes and last runs are saved to it.
+	// Schedules are kept only in memory, when empty.
+	File string
+
+	// Policy of missed runs. Default is skip.
+	CatchUp CatchUp
+
+	// Max number of concurrent captures. Default is 2.
+	MaxConcurrent int
+}
+
+func (opts *Opts) getMaxConcurrent() int {
+	if opts.MaxConcurrent <= 0 {
+		return defaultMaxConcurrent
+	}
+
+	return opts.MaxConcurrent
+}
+
+// Scheduler captures pages by cron expressions and saves them as snapshots.
+type Scheduler struct {
+	service *service.Service
+	parse   ParseFunc
+	opts    Opts
+
+	lock    sync.Mutex
+	entries map[string]*entry
+
+	// ids of schedules being captured, so replaced schedule doesn't run concurrently with previous one
+	running map[string]struct{}
+
+	// wake interrupts sleep, when schedules are changed
+	wake chan struct{}
+}
+
+type entry struct {
+	schedule *Schedule
+	cron     *Cron
+	opts     service.ShotOpts
+
+	next time.Time
+}
+
+// New creates scheduler and loads schedules from file.
+func New(ctx context.Context, srv *service.Service, parse ParseFunc, opts Opts) (*Scheduler, error) {
+	scheduler := &Scheduler{
+		service: srv,
+		parse:   parse,
+		opts:    opts,
+		entries: make(map[string]*entry),
+		running: make(map[string]struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+
+	if opts.File == "" {
+		return scheduler, nil
+	}
+
+	schedules, err := loadFile(opts.File)
+	if err != nil {
+		return nil, xerrors.Errorf("load file '%s': %w", opts.File, err)
+	}
+
+	now := time.Now()
+
+	for _, schedule := range schedules {
+		entry, err := scheduler.newEntry(schedule)
+		if err != nil {
+			return nil, xerrors.Errorf("schedule '%s': %w", schedule.ID, err)
+		}
+
+		entry.next = scheduler.firstRun(ctx, entry, now)
+
+		scheduler.entries[schedule.ID] = entry
+	}
+
+	return scheduler, nil
+}
+
+func (scheduler *Scheduler) newEntry(schedule *Schedule) (*entry, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return nil, xerrors.Errorf("parse cron: %w", err)
+	}
+
+	opts, err := scheduler.parse(schedule.URL, schedule.Params)
+	if err != nil {
+		return nil, xerrors.Errorf("parse params: %w", err)
+	}
+
+	return &entry{
+		schedule: schedule,
+		cron:     cron,
+		opts:     opts,
+	}, nil
+}
+
+// firstRun returns time of first run after start according to catch up policy.
+func (scheduler *Scheduler) firstRun(ctx context.Context, entry *entry, now time.Time) time.Time {
+	if entry.schedule.LastRun == nil {
+		return entry.cron.Next(now)
+	}
+
+	missed := entry.cron.Next(*entry.schedule.LastRun)
+	if missed.IsZero() || missed.After(now) {
+		return missed
+	}
+
+	if scheduler.opts.CatchUp == CatchUpOnce {
+		log.Ctx(ctx).Info().
+			Str("schedule", entry.schedule.ID).
+			Time("missed", missed).
+			Msg("catch up missed run")
+
+		return now
+	}
+
+	return entry.cron.Next(now)
+}
+
+// List returns schedules sorted by id.
+func (scheduler *Scheduler) List() []*Schedule {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	return scheduler.schedules()
+}
+
+// schedules returns copy of schedules sorted by id, should be called under lock.
+func (scheduler *Scheduler) schedules() []*Schedule {
+	result := make([]*Schedule, 0, len(scheduler.entries))
+
+	for _, entry := range scheduler.entries {
+		cpy := *entry.schedule
+		result = append(result, &cpy)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
+// Put adds or replaces schedule.
+func (scheduler *Scheduler) Put(ctx context.Context, schedule *Schedule) error {
+	entry, err := scheduler.newEntry(schedule)
+	if err != nil {
+		return xerrors.Errorf("%v: %w", err, ErrInvalidSchedule)
+	}
+
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	if prev, ok := scheduler.entries[schedule.ID]; ok {
+		schedule.LastRun = prev.schedule.LastRun
+	}
+
+	entry.next = entry.cron.Next(time.Now())
+
+	scheduler.entries[schedule.ID] = entry
+
+	scheduler.notify()
+
+	return scheduler.save(ctx)
+}
+
+// Delete removes schedule by id.
+func (scheduler *Scheduler) Delete(ctx context.Context, id string) error {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	if _, ok := scheduler.entries[id]; !ok {
+		return ErrScheduleNotFound
+	}
+
+	delete(scheduler.entries, id)
+
+	return scheduler.save(ctx)
+}
+
+// save writes schedules to file, should be called under lock.
+func (scheduler *Scheduler) save(ctx context.Context) error {
+	if scheduler.opts.File == "" {
+		return nil
+	}
+
+	if err := saveFile(scheduler.opts.File, scheduler.schedules()); err != nil {
+		return xerrors.Errorf("save file '%s': %w", scheduler.opts.File, err)
+	}
+
+	return nil
+}
+
+func (scheduler *Scheduler) notify() {
+	select {
+	case scheduler.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run captures due schedules until ctx is done.
+func (scheduler *Scheduler) Run(ctx context.Context) {
+	slots := make(chan struct{}, scheduler.opts.getMaxConcurrent())
+	wg := &sync.WaitGroup{}
+
+	defer wg.Wait()
+
+	for {
+		due, sleep := scheduler.due(time.Now())
+
+		for _, item := range due {
+			wg.Add(1)
+
+			go func(item *entry) {
+				defer wg.Done()
+
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					scheduler.finish(ctx, item, time.Time{})
+					return
+				}
+
+				defer func() { <-slots }()
+
+				scheduler.capture(ctx, item)
+			}(item)
+		}
+
+		timer := time.NewTimer(sleep)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-scheduler.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// due marks entries to run at now as running and returns them with time to sleep before next check.
+func (scheduler *Scheduler) due(now time.Time) ([]*entry, time.Duration) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	var result []*entry
+
+	sleep := maxSleep
+
+	for id, entry := range scheduler.entries {
+		if _, ok := scheduler.running[id]; ok || entry.next.IsZero() {
+			continue
+		}
+
+		if !entry.next.After(now) {
+			scheduler.running[id] = struct{}{}
+			result = append(result, entry)
+
+			continue
+		}
+
+		if d := entry.next.Sub(now); d < sleep {
+			sleep = d
+		}
+	}
+
+	return result, sleep
+}
+
+func (scheduler *Scheduler) capture(ctx context.Context, entry *entry) {
+	started := time.Now()
+
+	logger := log.Ctx(ctx).With().Str("schedule", entry.schedule.ID).Logger()
+	ctx = logger.WithContext(ctx)
+
+	opts := entry.opts
+	opts.Cache.Fresh = true
+	opts.Cache.Snapshot = started
+
+	result, err := scheduler.service.Shot(ctx, entry.schedule.URL, opts)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("url", entry.schedule.URL).Msg("scheduled capture failed")
+	} else {
+		log.Ctx(ctx).Info().
+			Str("url", entry.schedule.URL).
+			Str("cache", string(result.Cache)).
+			Dur("took", time.Since(started)).
+			Msg("scheduled capture")
+	}
+
+	scheduler.finish(ctx, entry, started)
+}
+
+// finish schedules next run of entry, zero started means entry was not run.
+func (scheduler *Scheduler) finish(ctx context.Context, entry *entry, started time.Time) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	delete(scheduler.running, entry.schedule.ID)
+
+	if started.IsZero() {
+		return
+	}
+
+	entry.schedule.LastRun = &started
+	entry.next = entry.cron.Next(started)
+
+	// entry is replaced during capture
+	if current, ok := scheduler.entries[entry.schedule.ID]; ok && current != entry {
+		current.schedule.LastRun = &started
+	}
+
+	if err := scheduler.save(ctx); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("save schedules")
+	}
+}
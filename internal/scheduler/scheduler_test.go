@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bots-house/webshot/internal/service"
+)
+
+func parseTestParams(url string, params map[string]interface{}) (service.ShotOpts, error) {
+	return service.ShotOpts{}, nil
+}
+
+func TestSchedulerPutWhileRunning(t *testing.T) {
+	ctx := context.Background()
+
+	scheduler, err := New(ctx, nil, parseTestParams, Opts{})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	if err := scheduler.Put(ctx, &Schedule{ID: "a", Cron: "* * * * *", URL: "https://example.com"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	later := time.Now().Add(time.Hour)
+
+	due, _ := scheduler.due(later)
+	if len(due) != 1 {
+		t.Fatalf("%d entries are due, expected 1", len(due))
+	}
+
+	// replacement is not run until previous run is finished
+	if err := scheduler.Put(ctx, &Schedule{ID: "a", Cron: "*/5 * * * *", URL: "https://example.com"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	if replaced, _ := scheduler.due(later); len(replaced) != 0 {
+		t.Fatalf("%d entries are due during run, expected 0", len(replaced))
+	}
+
+	scheduler.finish(ctx, due[0], time.Now())
+
+	if replaced, _ := scheduler.due(later); len(replaced) != 1 || replaced[0].schedule.Cron != "*/5 * * * *" {
+		t.Fatalf("replacement is not due after run: %v", replaced)
+	}
+
+	if list := scheduler.List(); len(list) != 1 || list[0].LastRun == nil {
+		t.Errorf("last run is not kept by replacement: %+v", list)
+	}
+}
+
+func TestLoadFileNumbers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+
+	content := `{"schedules": [{"id": "a", "cron": "@daily", "url": "https://example.com", "params": {"ttl": 2592000, "scale": 1.5}}]}`
+
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	schedules, err := loadFile(path)
+	if err != nil {
+		t.Fatalf("load file: %v", err)
+	}
+
+	if len(schedules) != 1 {
+		t.Fatalf("%d schedules are loaded, expected 1", len(schedules))
+	}
+
+	// params are formatted as query values, large numbers should not get exponent
+	for name, want := range map[string]string{"ttl": "2592000", "scale": "1.5"} {
+		v := schedules[0].Params[name]
+
+		if _, ok := v.(json.Number); !ok {
+			t.Errorf("%s is %T, expected json.Number", name, v)
+		}
+
+		if got := fmt.Sprint(v); got != want {
+			t.Errorf("%s is formatted as %s, expected %s", name, got, want)
+		}
+	}
+}
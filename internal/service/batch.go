@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+)
+
+const defaultBatchParallelism = 4
+
+// BatchItem is single screenshot of batch.
+type BatchItem struct {
+	URL  string
+	Opts ShotOpts
+}
+
+// BatchResult is result of single item of batch.
+type BatchResult struct {
+	// Index of item in batch
+	Index int
+	Item  BatchItem
+
+	// Image, nil when Err is set
+	Body  []byte
+	Cache CacheStatus
+
+	Took time.Duration
+	Err  error
+}
+
+// ShotBatch takes screenshots of items with bounded parallelism.
+// Results are passed to fn in order of completion from calling goroutine,
+// error of item does not abort batch, but error of fn does.
+func (srv *Service) ShotBatch(
+	ctx context.Context,
+	items []BatchItem,
+	parallelism int,
+	fn func(result *BatchResult) error,
+) error {
+	if parallelism <= 0 {
+		parallelism = defaultBatchParallelism
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indexes := make(chan int)
+	results := make(chan *BatchResult)
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < parallelism && i < len(items); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx := range indexes {
+				result := srv.shotBatchItem(ctx, idx, items[idx])
+
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(indexes)
+
+		for i := range items {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		if err := fn(result); err != nil {
+			// stop workers, results channel is drained by them on ctx done
+			cancel()
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+func (srv *Service) shotBatchItem(ctx context.Context, idx int, item BatchItem) *BatchResult {
+	started := time.Now()
+
+	result := &BatchResult{
+		Index: idx,
+		Item:  item,
+	}
+
+	output, err := srv.Shot(ctx, item.URL, item.Opts)
+	if err == nil {
+		result.Cache = output.Cache
+		result.Body, err = io.ReadAll(output.Body)
+	}
+
+	result.Took = time.Since(started)
+
+	if err != nil {
+		result.Err = xerrors.Errorf("shot: %w", err)
+
+		log.Ctx(ctx).Warn().
+			Err(err).
+			Int("index", idx).
+			Str("url", item.URL).
+			Msg("batch item failed")
+	}
+
+	return result
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// inflight deduplicates concurrent calls with same key.
+// Call runs in own context, which is canceled only when all waiters are gone.
+type inflight struct {
+	lock  sync.Mutex
+	calls map[string]*inflightCall
+}
+
+type inflightCall struct {
+	done   chan struct{}
+	output *rendered
+	err    error
+
+	waiters int
+	total   int
+	cancel  context.CancelFunc
+}
+
+// do runs fn or waits for result of running call with same key.
+// Shared reports whether result was produced by call of other waiter.
+func (group *inflight) do(
+	ctx context.Context,
+	key string,
+	fn func(ctx context.Context) (*rendered, error),
+) (output *rendered, shared bool, err error) {
+	group.lock.Lock()
+
+	if group.calls == nil {
+		group.calls = make(map[string]*inflightCall)
+	}
+
+	call, shared := group.calls[key]
+	if shared {
+		call.waiters++
+		call.total++
+	} else {
+		// call should outlive request, so only logger is inherited
+		callCtx, cancel := context.WithCancel(log.Ctx(ctx).WithContext(context.Background()))
+
+		call = &inflightCall{
+			done:    make(chan struct{}),
+			waiters: 1,
+			total:   1,
+			cancel:  cancel,
+		}
+
+		group.calls[key] = call
+
+		go group.run(callCtx, key, call, fn)
+	}
+
+	group.lock.Unlock()
+
+	select {
+	case <-call.done:
+		return call.output, shared, call.err
+	case <-ctx.Done():
+		group.leave(key, call)
+		return nil, shared, ctx.Err()
+	}
+}
+
+func (group *inflight) run(
+	ctx context.Context,
+	key string,
+	call *inflightCall,
+	fn func(ctx context.Context) (*rendered, error),
+) {
+	defer call.cancel()
+
+	call.output, call.err = fn(ctx)
+
+	group.lock.Lock()
+
+	if group.calls[key] == call {
+		delete(group.calls, key)
+	}
+
+	total := call.total
+
+	group.lock.Unlock()
+
+	close(call.done)
+
+	if total > 1 {
+		log.Ctx(ctx).Info().
+			Int("waiters", total).
+			Int("coalesced", total-1).
+			Msg("shot coalesced")
+	}
+}
+
+// leave removes waiter from call and aborts call when nobody waits for it.
+func (group *inflight) leave(key string, call *inflightCall) {
+	group.lock.Lock()
+	defer group.lock.Unlock()
+
+	call.waiters--
+
+	if call.waiters > 0 {
+		return
+	}
+
+	// new waiters should start new call instead of joining aborted one
+	if group.calls[key] == call {
+		delete(group.calls, key)
+	}
+
+	call.cancel()
+}
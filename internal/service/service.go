@@ -9,18 +9,38 @@ import (
 
 	"github.com/bots-house/webshot/internal/renderer"
 	"github.com/bots-house/webshot/internal/storage"
+	"github.com/bots-house/webshot/internal/templates"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/xerrors"
 )
 
 type Service struct {
-	Renderer renderer.Renderer
-	Storage  storage.Storage
+	Renderer  renderer.Renderer
+	Storage   storage.Storage
+	Templates *templates.Dir
+
+	// Window after TTL, when expired image is served and refreshed in background.
+	// Can be overridden by CacheOpts.StaleTTL.
+	StaleTTL time.Duration
+
+	// identical concurrent shots share single render
+	inflight inflight
 }
 
 type CacheOpts struct {
 	TTL   time.Duration
 	Fresh bool
+
+	// Window after TTL, when expired image is served and refreshed in background.
+	// Nil means default of service.
+	StaleTTL *time.Duration
+
+	// When set, image is saved as snapshot taken at this time
+	// and cached image is not replaced.
+	Snapshot time.Time
+
+	// When set, body of cached image with public URL is not loaded, only URL is returned
+	URLOnly bool
 }
 
 func (opts *CacheOpts) getTTL() time.Duration {
@@ -31,46 +51,96 @@ func (opts *CacheOpts) getTTL() time.Duration {
 	return opts.TTL
 }
 
+func (srv *Service) getStaleTTL(opts *CacheOpts) time.Duration {
+	if opts.StaleTTL == nil {
+		return srv.StaleTTL
+	}
+
+	return *opts.StaleTTL
+}
+
 type ShotOpts struct {
 	Render renderer.Opts
 	Cache  CacheOpts
 }
 
+// CacheStatus describes how result of Shot is related to cache.
+type CacheStatus string
+
+const (
+	// CacheMiss means image is rendered by request.
+	CacheMiss CacheStatus = "miss"
+
+	// CacheHit means image is served from cache.
+	CacheHit CacheStatus = "hit"
+
+	// CacheStale means expired image is served from cache and refreshed in background.
+	CacheStale CacheStatus = "stale"
+)
+
+// Result is image returned by Shot.
+type Result struct {
+	// Body is nil, when only URL of cached image is requested
+	Body  io.Reader
+	Cache CacheStatus
+
+	// Age of cached image
+	Age time.Duration
+
+	// Key of image in storage, empty without storage
+	Key string
+
+	// Public URL of image in storage, empty when storage can't serve it
+	URL string
+}
+
+// rendered is image produced by render, file is nil without storage.
+type rendered struct {
+	body []byte
+	file *storage.File
+}
+
 func (srv *Service) Shot(
 	ctx context.Context,
 	targetURL string,
 	opts ShotOpts,
-) (io.Reader, error) {
+) (*Result, error) {
 	if srv.Storage == nil {
 		return srv.shotNoStorage(ctx, targetURL, opts.Render)
 	}
 
-	u, err := url.Parse(targetURL)
+	meta, err := newMeta(targetURL, &opts.Render)
 	if err != nil {
-		return nil, xerrors.Errorf("parse url: %w", err)
+		return nil, err
 	}
 
-	meta := storage.Meta{
-		URL:    u,
-		Opts:   opts.Render.Hash(),
-		Format: opts.Render.Format,
+	key := targetURL + "\n" + opts.Render.Hash()
+
+	// snapshot should be uploaded even if same image is rendered for other request
+	if !opts.Cache.Snapshot.IsZero() {
+		key += "\n" + opts.Cache.Snapshot.String()
 	}
 
-	renderAndSave := func(ctx context.Context) (io.Reader, error) {
-		output, err := srv.Renderer.Render(ctx, targetURL, opts.Render)
-		if err != nil {
-			return nil, xerrors.Errorf("render error: %w", err)
-		}
+	renderAndSave := func(ctx context.Context) (*Result, error) {
+		return srv.coalesce(ctx, key, func(ctx context.Context) (*rendered, error) {
+			output, err := srv.Renderer.Render(ctx, targetURL, opts.Render)
+			if err != nil {
+				return nil, xerrors.Errorf("render error: %w", err)
+			}
 
-		if err := srv.Storage.Upload(ctx, storage.Upload{
-			Meta: meta,
-			TTL:  opts.Cache.getTTL(),
-			Body: bytes.NewReader(output),
-		}); err != nil {
-			return nil, xerrors.Errorf("upload to stroge: %w", err)
-		}
+			file, err := srv.Storage.Upload(ctx, storage.Upload{
+				Meta:     meta,
+				TTL:      opts.Cache.getTTL(),
+				StaleTTL: srv.getStaleTTL(&opts.Cache),
+				Body:     bytes.NewReader(output),
+				Snapshot: opts.Cache.Snapshot,
+			})
+			if err != nil {
+				return nil, xerrors.Errorf("upload to stroge: %w", err)
+			}
 
-		return bytes.NewReader(output), nil
+			return &rendered{body: output, file: file}, nil
+		})
 	}
 
 	// if client want fresh screen
@@ -78,23 +148,230 @@ func (srv *Service) Shot(
 		return renderAndSave(ctx)
 	}
 
-	// try to use cached image
-	body, err := srv.Storage.Get(ctx, meta)
-	if err == storage.ErrFileNotFound || err == storage.ErrFileExpired || err == storage.ErrFileCorrupted {
+	// try to use cached image, body is loaded only when it's served
+	file, err := srv.Storage.Stat(ctx, meta)
+	if err == storage.ErrFileNotFound || err == storage.ErrFileCorrupted {
 		log.Ctx(ctx).Debug().Err(err).Msg("something wrong with file, render new")
 		return renderAndSave(ctx)
 	} else if err != nil {
-		return nil, xerrors.Errorf("storage get: %w", err)
+		return nil, xerrors.Errorf("storage stat: %w", err)
 	}
 
-	return body, nil
+	staleTTL := srv.getStaleTTL(&opts.Cache)
+
+	if file.Age > file.TTL+staleTTL {
+		log.Ctx(ctx).Debug().Dur("age", file.Age).Msg("file expired, render new")
+		return renderAndSave(ctx)
+	}
+
+	var body io.Reader
+
+	if !opts.Cache.URLOnly || file.URL == "" {
+		body, err = srv.Storage.Open(ctx, file.Key)
+		if err == storage.ErrFileNotFound {
+			log.Ctx(ctx).Debug().Str("key", file.Key).Msg("latest file is missing, render new")
+			return renderAndSave(ctx)
+		} else if err != nil {
+			return nil, xerrors.Errorf("storage open: %w", err)
+		}
+	}
+
+	if !file.IsExpired() {
+		return &Result{Body: body, Cache: CacheHit, Age: file.Age, Key: file.Key, URL: file.URL}, nil
+	}
+
+	log.Ctx(ctx).Debug().
+		Dur("age", file.Age).
+		Dur("stale_ttl", staleTTL).
+		Msg("file is stale, refresh in background")
+
+	// refresh should outlive request, so only logger is inherited
+	refreshCtx := log.Ctx(ctx).WithContext(context.Background())
+
+	go func() {
+		if _, err := renderAndSave(refreshCtx); err != nil {
+			log.Ctx(refreshCtx).Error().Err(err).Msg("refresh stale file")
+		}
+	}()
+
+	return &Result{Body: body, Cache: CacheStale, Age: file.Age, Key: file.Key, URL: file.URL}, nil
 }
 
-func (srv *Service) shotNoStorage(ctx context.Context, url string, opts renderer.Opts) (io.Reader, error) {
-	output, err := srv.Renderer.Render(ctx, url, opts)
+// ShotTemplate renders named template with data and takes screenshot of it
+// at size defined by template.
+func (srv *Service) ShotTemplate(
+	ctx context.Context,
+	name string,
+	data map[string]string,
+	opts ShotOpts,
+) (*Result, error) {
+	if srv.Templates == nil {
+		return nil, templates.ErrTemplateNotFound
+	}
+
+	tmpl, err := srv.Templates.Get(name)
 	if err != nil {
-		return nil, xerrors.Errorf("render error: %w", err)
+		return nil, xerrors.Errorf("get template: %w", err)
 	}
 
-	return bytes.NewReader(output), nil
+	content, err := tmpl.Execute(data)
+	if err != nil {
+		return nil, xerrors.Errorf("execute template '%s': %w", name, err)
+	}
+
+	opts.Render.HTML = content
+	opts.Render.Width = tmpl.Width
+	opts.Render.Height = tmpl.Height
+
+	return srv.Shot(ctx, "", opts)
+}
+
+func (srv *Service) shotNoStorage(ctx context.Context, url string, opts renderer.Opts) (*Result, error) {
+	return srv.coalesce(ctx, url+"\n"+opts.Hash(), func(ctx context.Context) (*rendered, error) {
+		output, err := srv.Renderer.Render(ctx, url, opts)
+		if err != nil {
+			return nil, xerrors.Errorf("render error: %w", err)
+		}
+
+		return &rendered{body: output}, nil
+	})
+}
+
+// coalesce runs fn once for concurrent shots with same key.
+// Render is aborted when all waiting clients are gone.
+func (srv *Service) coalesce(
+	ctx context.Context,
+	key string,
+	fn func(ctx context.Context) (*rendered, error),
+) (*Result, error) {
+	output, shared, err := srv.inflight.do(ctx, key, fn)
+
+	if shared {
+		log.Ctx(ctx).Debug().Msg("shot is shared with concurrent request")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Body: bytes.NewReader(output.body), Cache: CacheMiss}
+
+	if output.file != nil {
+		result.Key = output.file.Key
+		result.URL = output.file.URL
+	}
+
+	return result, nil
+}
+
+func newMeta(targetURL string, opts *renderer.Opts) (storage.Meta, error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return storage.Meta{}, xerrors.Errorf("parse url: %w", err)
+	}
+
+	return storage.Meta{
+		URL:     u,
+		Content: opts.ContentHash(),
+		Opts:    opts.Hash(),
+		Format:  opts.Format,
+	}, nil
+}
+
+// PurgeQuery selects cached screenshots to purge, one of fields should be set.
+type PurgeQuery struct {
+	// Screenshot of URL with exact options
+	URL  string
+	Opts *renderer.Opts
+
+	// Screenshots of URL with any options, URL should be set
+	AllOpts bool
+
+	// All screenshots of sites with hostname
+	Host string
+}
+
+// PurgeResult is outcome of Purge.
+type PurgeResult struct {
+	// Number of removed objects
+	Deleted int
+
+	// Number of screenshots of host, which are not matched by URL,
+	// because they were cached before hash of URL was saved.
+	// They can be purged by host.
+	Unindexed int
+}
+
+// Purge removes cached screenshots matching query.
+func (srv *Service) Purge(ctx context.Context, query PurgeQuery) (*PurgeResult, error) {
+	result := &PurgeResult{}
+
+	if srv.Storage == nil {
+		return result, nil
+	}
+
+	var (
+		storageQuery storage.Query
+		byURL        *url.URL
+	)
+
+	switch {
+	case query.Host != "":
+		storageQuery.Host = query.Host
+	case query.AllOpts:
+		u, err := url.Parse(query.URL)
+		if err != nil {
+			return nil, xerrors.Errorf("parse url: %w", err)
+		}
+
+		// empty host would select all screenshots
+		if u.Hostname() == "" {
+			return nil, xerrors.Errorf("url without host: %w", storage.ErrInvalidQuery)
+		}
+
+		storageQuery.Host = u.Hostname()
+		byURL = u
+	case query.Opts != nil:
+		meta, err := newMeta(query.URL, query.Opts)
+		if err != nil {
+			return nil, err
+		}
+
+		storageQuery.Meta = &meta
+	default:
+		return nil, xerrors.New("empty purge query")
+	}
+
+	objects, err := srv.Storage.List(ctx, storageQuery)
+	if err != nil {
+		return nil, xerrors.Errorf("list: %w", err)
+	}
+
+	if byURL != nil {
+		objects, result.Unindexed = storage.FilterByURL(objects, byURL)
+	}
+
+	// temp files are being written, rename of them should not fail
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		if !object.Temp {
+			keys = append(keys, object.Key)
+		}
+	}
+
+	if err := srv.Storage.Delete(ctx, keys); err != nil {
+		return nil, xerrors.Errorf("delete: %w", err)
+	}
+
+	result.Deleted = len(keys)
+
+	log.Ctx(ctx).Info().
+		Str("url", query.URL).
+		Str("host", query.Host).
+		Bool("all_opts", query.AllOpts).
+		Int("objects", result.Deleted).
+		Int("unindexed", result.Unindexed).
+		Msg("purge cache")
+
+	return result, nil
 }
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+)
+
+const defaultGCMinAge = time.Hour
+
+// gcKeyRegexp matches keys of links and images uploaded by storage, e.g. host/hash.xid.png,
+// and temp files of them, e.g. host/hash.link.123.tmp.
+var gcKeyRegexp = regexp.MustCompile(`(^|/)[^/]+/[0-9a-f]{15}\.(link|[0-9a-v]{20}\.[a-z]+)(\.[0-9]+\.tmp)?$`)
+
+// GC deletes images, which are not pointed by links, and expired screenshots.
+// Only keys of links and images are deleted, other files and snapshots are kept.
+type GC struct {
+	Storage Storage
+
+	// Expired screenshot is kept during this window after TTL, because it can be served as stale.
+	// Window saved in link is used, when it's longer.
+	StaleTTL time.Duration
+
+	// Image, which is not pointed by link, is deleted only when it's older,
+	// so image uploaded before its link is kept. Default is 1h.
+	MinAge time.Duration
+
+	// Only report objects to delete
+	DryRun bool
+}
+
+func (gc *GC) getMinAge() time.Duration {
+	if gc.MinAge <= 0 {
+		return defaultGCMinAge
+	}
+
+	return gc.MinAge
+}
+
+// GCReport is result of garbage collection.
+type GCReport struct {
+	// Number of listed objects
+	Scanned int
+
+	// Number of expired links and images of them
+	Expired int
+
+	// Number of images, which are not pointed by links, and stale temp files
+	Orphaned int
+
+	// Number of objects, which are not links or images, they are kept
+	Foreign int
+
+	// Size of deleted objects
+	Bytes int64
+}
+
+// Collect deletes garbage objects once.
+func (gc *GC) Collect(ctx context.Context) (*GCReport, error) {
+	now := time.Now()
+
+	objects, err := gc.Storage.List(ctx, Query{})
+	if err != nil {
+		return nil, xerrors.Errorf("list: %w", err)
+	}
+
+	report := &GCReport{Scanned: len(objects)}
+
+	// targets of live and expired links
+	live := make(map[string]struct{})
+	expired := make(map[string]struct{})
+
+	for _, object := range objects {
+		if !object.Link {
+			continue
+		}
+
+		if gc.isExpired(object, now) {
+			expired[object.Target] = struct{}{}
+		} else {
+			live[object.Target] = struct{}{}
+		}
+	}
+
+	var keys []string
+
+	for _, object := range objects {
+		if !gcKeyRegexp.MatchString(object.Key) {
+			report.Foreign++
+			continue
+		}
+
+		if _, ok := live[object.Key]; ok {
+			continue
+		}
+
+		_, isExpiredTarget := expired[object.Key]
+
+		switch {
+		case object.Link && !gc.isExpired(object, now):
+			continue
+		case object.Link || isExpiredTarget:
+			report.Expired++
+		case object.ModTime.Add(gc.getMinAge()).After(now):
+			continue
+		default:
+			report.Orphaned++
+		}
+
+		keys = append(keys, object.Key)
+		report.Bytes += object.Size
+	}
+
+	if !gc.DryRun && len(keys) > 0 {
+		if err := gc.Storage.Delete(ctx, keys); err != nil {
+			return nil, xerrors.Errorf("delete: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+func (gc *GC) isExpired(object *Object, now time.Time) bool {
+	staleTTL := gc.StaleTTL
+
+	if object.StaleTTL != nil && *object.StaleTTL > staleTTL {
+		staleTTL = *object.StaleTTL
+	}
+
+	return object.IsExpired(now.Add(-staleTTL))
+}
+
+// Run collects garbage with interval until ctx is done.
+func (gc *GC) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		started := time.Now()
+
+		report, err := gc.Collect(ctx)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("storage gc failed")
+			continue
+		}
+
+		log.Ctx(ctx).Info().
+			Bool("dry_run", gc.DryRun).
+			Int("scanned", report.Scanned).
+			Int("expired", report.Expired).
+			Int("orphaned", report.Orphaned).
+			Int("foreign", report.Foreign).
+			Int64("bytes", report.Bytes).
+			Dur("took", time.Since(started)).
+			Msg("storage gc")
+	}
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeStorage lists fixed objects and records deleted keys.
+type fakeStorage struct {
+	objects []*Object
+	deleted []string
+}
+
+func (s *fakeStorage) Stat(ctx context.Context, meta Meta) (*File, error) {
+	return nil, ErrFileNotFound
+}
+
+func (s *fakeStorage) Open(ctx context.Context, key string) (io.Reader, error) {
+	return nil, ErrFileNotFound
+}
+
+func (s *fakeStorage) Upload(ctx context.Context, upload Upload) (*File, error) {
+	return &File{Key: "unused"}, nil
+}
+
+func (s *fakeStorage) List(ctx context.Context, query Query) ([]*Object, error) {
+	return s.objects, nil
+}
+
+func (s *fakeStorage) Delete(ctx context.Context, keys []string) error {
+	s.deleted = append(s.deleted, keys...)
+	return nil
+}
+
+const (
+	gcTestXID1 = "c3v1k2a6n5ds73b0k1a0"
+	gcTestXID2 = "c3v1k2a6n5ds73b0k1b0"
+)
+
+func TestGCCollect(t *testing.T) {
+	now := time.Now()
+	hour := time.Hour
+	day := 24 * time.Hour
+
+	link := func(key, target string, age, ttl time.Duration) *Object {
+		return &Object{Key: key, Size: 1, ModTime: now.Add(-age), Link: true, Target: target, TTL: ttl}
+	}
+
+	file := func(key string, age time.Duration) *Object {
+		return &Object{Key: key, Size: 10, ModTime: now.Add(-age)}
+	}
+
+	for _, test := range []struct {
+		name    string
+		gc      GC
+		objects []*Object
+		deleted []string
+		report  GCReport
+	}{
+		{
+			name: "live link and its image are kept, previous image is orphan",
+			objects: []*Object{
+				link("s/example.com/0123456789abcde.link", "s/example.com/0123456789abcde."+gcTestXID2+".png", hour, day),
+				file("s/example.com/0123456789abcde."+gcTestXID2+".png", hour),
+				file("s/example.com/0123456789abcde."+gcTestXID1+".png", 2*hour),
+			},
+			deleted: []string{"s/example.com/0123456789abcde." + gcTestXID1 + ".png"},
+			report:  GCReport{Scanned: 3, Orphaned: 1, Bytes: 10},
+		},
+		{
+			name: "expired link and its image are deleted",
+			objects: []*Object{
+				link("_html/0123456789abcde.link", "_html/0123456789abcde."+gcTestXID1+".pdf", 2*day, day),
+				file("_html/0123456789abcde."+gcTestXID1+".pdf", 2*day),
+			},
+			deleted: []string{
+				"_html/0123456789abcde." + gcTestXID1 + ".pdf",
+				"_html/0123456789abcde.link",
+			},
+			report: GCReport{Scanned: 2, Expired: 2, Bytes: 11},
+		},
+		{
+			name: "expired link is kept during stale window of gc",
+			gc:   GC{StaleTTL: 2 * day},
+			objects: []*Object{
+				link("example.com/0123456789abcde.link", "example.com/0123456789abcde."+gcTestXID1+".png", 2*day, day),
+				file("example.com/0123456789abcde."+gcTestXID1+".png", 2*day),
+			},
+			report: GCReport{Scanned: 2},
+		},
+		{
+			name: "expired link is kept during stale window saved in it",
+			objects: []*Object{
+				func() *Object {
+					object := link("example.com/0123456789abcde.link", "example.com/0123456789abcde."+gcTestXID1+".png", 2*day, day)
+					staleTTL := 2 * day
+					object.StaleTTL = &staleTTL
+					return object
+				}(),
+				file("example.com/0123456789abcde."+gcTestXID1+".png", 2*day),
+			},
+			report: GCReport{Scanned: 2},
+		},
+		{
+			name: "recent orphan and temp files are kept until min age",
+			gc:   GC{MinAge: 3 * hour},
+			objects: []*Object{
+				file("example.com/0123456789abcde."+gcTestXID1+".png", 2*hour),
+				{Key: "example.com/0123456789abcde.link.123.tmp", Size: 5, ModTime: now.Add(-hour), Temp: true},
+				{Key: "example.com/fedcba987654321." + gcTestXID1 + ".png.456.tmp", Size: 5, ModTime: now.Add(-4 * hour), Temp: true},
+			},
+			deleted: []string{"example.com/fedcba987654321." + gcTestXID1 + ".png.456.tmp"},
+			report:  GCReport{Scanned: 3, Orphaned: 1, Bytes: 5},
+		},
+		{
+			name: "foreign files are kept",
+			objects: []*Object{
+				file("backup.tar", day),
+				file("assets/logo.png", day),
+				file("example.com/0123456789abcde.png", day),
+				file("0123456789abcde."+gcTestXID1+".png", day),
+				file("example.com/0123456789ABCDE."+gcTestXID1+".png", day),
+				link("docs/readme.link", "", day, 0),
+			},
+			report: GCReport{Scanned: 6, Foreign: 6},
+		},
+		{
+			name: "dry run reports without delete",
+			gc:   GC{DryRun: true},
+			objects: []*Object{
+				link("example.com/0123456789abcde.link", "example.com/0123456789abcde."+gcTestXID1+".png", 2*day, day),
+				file("example.com/0123456789abcde."+gcTestXID1+".png", 2*day),
+				file("example.com/fedcba987654321."+gcTestXID2+".webp", 2*day),
+			},
+			report: GCReport{Scanned: 3, Expired: 2, Orphaned: 1, Bytes: 21},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			storage := &fakeStorage{objects: test.objects}
+
+			gc := test.gc
+			gc.Storage = storage
+
+			report, err := gc.Collect(context.Background())
+			if err != nil {
+				t.Fatalf("collect: %v", err)
+			}
+
+			if *report != test.report {
+				t.Errorf("report is %+v, expected %+v", *report, test.report)
+			}
+
+			sort.Strings(storage.deleted)
+			sort.Strings(test.deleted)
+
+			if len(storage.deleted) != len(test.deleted) {
+				t.Fatalf("deleted %v, expected %v", storage.deleted, test.deleted)
+			}
+
+			for i := range test.deleted {
+				if storage.deleted[i] != test.deleted[i] {
+					t.Fatalf("deleted %v, expected %v", storage.deleted, test.deleted)
+				}
+			}
+		})
+	}
+}
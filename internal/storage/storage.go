@@ -2,8 +2,11 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/bots-house/webshot/internal"
@@ -12,9 +15,13 @@ import (
 
 // Meta contains metadata about screenshot
 type Meta struct {
-	// URL of target site
+	// URL of target site.
+	// For rendered HTML it's base URL, which can be empty.
 	URL *url.URL
 
+	// Hash of rendered HTML, empty when URL is rendered
+	Content string
+
 	// Hash of options
 	Opts string
 
@@ -27,18 +34,144 @@ type Upload struct {
 	Meta
 	TTL  time.Duration
 	Body io.Reader
+
+	// Window after TTL, when expired file can be served, it's saved for GC
+	StaleTTL time.Duration
+
+	// When set, file is saved as snapshot taken at this time
+	// and cached file is not replaced.
+	Snapshot time.Time
+}
+
+// File is screenshot found in storage, body is loaded by Storage.Open.
+type File struct {
+	// Key of latest file
+	Key string
+
+	// Time since upload
+	Age time.Duration
+
+	// TTL of upload
+	TTL time.Duration
+
+	// Public URL of file, empty when storage can't serve it
+	URL string
+}
+
+// IsExpired reports whether file is older than its TTL.
+func (file *File) IsExpired() bool {
+	return file.Age > file.TTL
 }
 
 var (
 	ErrFileNotFound  = xerrors.New("file not found")
 	ErrFileCorrupted = xerrors.New("file corrupted")
-	ErrFileExpired   = xerrors.New("file expired")
+	ErrInvalidQuery  = xerrors.New("invalid query")
 )
 
+// Query selects cached screenshots, one of fields should be set.
+// Empty query selects all cached screenshots, snapshots are never selected.
+// Screenshots of URL with any options are selected by host and FilterByURL.
+type Query struct {
+	// Screenshots of URL with exact options
+	Meta *Meta
+
+	// All screenshots of sites with hostname
+	Host string
+}
+
+// Validate checks that host can't select keys outside of its dir.
+func (query *Query) Validate() error {
+	if query.Host == "" {
+		return nil
+	}
+
+	if query.Host == "." || strings.Contains(query.Host, "..") || strings.ContainsAny(query.Host, `/\`) {
+		return xerrors.Errorf("host '%s': %w", query.Host, ErrInvalidQuery)
+	}
+
+	return nil
+}
+
+// Object is file in storage.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+
+	// Temp is file being written or left by crashed upload
+	Temp bool
+
+	// Link points to latest file of screenshot,
+	// following fields are set only for links.
+	Link bool
+
+	// Key of latest file
+	Target string
+
+	// TTL of latest file
+	TTL time.Duration
+
+	// Hash of URL, empty for links uploaded before it was saved
+	URLHash string
+
+	// Stale window of latest file, nil for links uploaded before it was saved
+	StaleTTL *time.Duration
+}
+
+// IsExpired reports whether link is older than its TTL.
+func (object *Object) IsExpired(now time.Time) bool {
+	return object.Link && now.After(object.ModTime.Add(object.TTL))
+}
+
+// HashURL returns hash of URL, which is saved in links.
+func HashURL(u *url.URL) string {
+	h := sha256.Sum256([]byte(u.String()))
+	return hex.EncodeToString(h[:])
+}
+
+// FilterByURL returns links of URL and files of them.
+// Links without hash of URL can't be matched, their number is returned too.
+func FilterByURL(objects []*Object, u *url.URL) ([]*Object, int) {
+	urlHash := HashURL(u)
+	hashes := make(map[string]struct{})
+	unindexed := 0
+
+	for _, object := range objects {
+		switch {
+		case !object.Link:
+		case object.URLHash == urlHash:
+			hashes[objectHash(object.Key)] = struct{}{}
+		case object.URLHash == "":
+			unindexed++
+		}
+	}
+
+	result := objects[:0]
+
+	for _, object := range objects {
+		if _, ok := hashes[objectHash(object.Key)]; ok {
+			result = append(result, object)
+		}
+	}
+
+	return result, unindexed
+}
+
 type Storage interface {
-	// Get returns URL of file in storage if it exists
-	Get(ctx context.Context, meta Meta) (io.Reader, error)
+	// Stat returns latest file of screenshot if it exists, even if it's expired.
+	// URL of file is set, when file is publicly available.
+	Stat(ctx context.Context, meta Meta) (*File, error)
+
+	// Open returns body of file by key
+	Open(ctx context.Context, key string) (io.Reader, error)
+
+	// Put image to storage, uploaded file is returned
+	Upload(ctx context.Context, upload Upload) (*File, error)
+
+	// List returns links and files matching query
+	List(ctx context.Context, query Query) ([]*Object, error)
 
-	// Put image to storage
-	Upload(ctx context.Context, upload Upload) error
+	// Delete removes objects by keys
+	Delete(ctx context.Context, keys []string) error
 }
@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+)
+
+// tmpExt is extension of files, which are being written.
+const tmpExt = ".tmp"
+
+// Local stores screenshots in directory with same layout as S3.
+// Keys are slash separated paths relative to directory.
+type Local struct {
+	dir string
+
+	// base URL of directory, e.g. CDN
+	publicURL string
+}
+
+// NewLocal creates storage in dir.
+// Files are served by publicURL, when it's empty, URL of file is not provided.
+func NewLocal(dir string, publicURL string) *Local {
+	return &Local{
+		dir:       dir,
+		publicURL: publicURL,
+	}
+}
+
+func (s *Local) getURL(key string) string {
+	if s.publicURL == "" {
+		return ""
+	}
+
+	return strings.TrimSuffix(s.publicURL, "/") + "/" + key
+}
+
+// localLink is content of link file, it points to latest file of screenshot.
+type localLink struct {
+	Latest  string `json:"latest"`
+	TTL     int64  `json:"ttl"`
+	URLHash string `json:"url_hash"`
+
+	// nil for links written before it was saved
+	StaleTTL *int64 `json:"stale_ttl,omitempty"`
+}
+
+// path returns path of file by key, key can't point outside of directory.
+func (s *Local) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func (s *Local) getLinkKey(in Meta) string {
+	dir, hash := getKey(in)
+
+	return path.Join(dir, hash+linkExt)
+}
+
+func (s *Local) getFileKey(in Meta) string {
+	dir, hash := getKey(in)
+
+	return path.Join(dir, fmt.Sprintf("%s.%s.%s", hash, xid.New().String(), in.Format.Ext()))
+}
+
+func (s *Local) getSnapshotKey(in Meta, at time.Time) string {
+	dir, hash := getKey(in)
+
+	return path.Join(snapshotsDir, dir, hash, at.UTC().Format(snapshotTimeLayout)+"."+in.Format.Ext())
+}
+
+// readLink returns link and its modification time.
+func (s *Local) readLink(key string) (*localLink, time.Time, error) {
+	f, err := os.Open(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, ErrFileNotFound
+	} else if err != nil {
+		return nil, time.Time{}, xerrors.Errorf("open link file: %w", err)
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, time.Time{}, xerrors.Errorf("stat link file: %w", err)
+	}
+
+	link := &localLink{}
+
+	if err := json.NewDecoder(f).Decode(link); err != nil || link.Latest == "" {
+		return nil, time.Time{}, ErrFileCorrupted
+	}
+
+	return link, info.ModTime(), nil
+}
+
+func (s *Local) Stat(ctx context.Context, in Meta) (*File, error) {
+	link, modTime, err := s.readLink(s.getLinkKey(in))
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Key: link.Latest,
+		Age: time.Since(modTime),
+		TTL: time.Duration(link.TTL) * time.Second,
+		URL: s.getURL(link.Latest),
+	}, nil
+}
+
+func (s *Local) Open(ctx context.Context, key string) (io.Reader, error) {
+	body, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, xerrors.Errorf("read file: %w", err)
+	}
+
+	return bytes.NewReader(body), nil
+}
+
+func (s *Local) Upload(ctx context.Context, in Upload) (*File, error) {
+	if !in.Snapshot.IsZero() {
+		return s.uploadSnapshot(ctx, in)
+	}
+
+	fileKey := s.getFileKey(in.Meta)
+	linkKey := s.getLinkKey(in.Meta)
+
+	defer func(s time.Time) {
+		log.Ctx(ctx).Debug().
+			Dur("took", time.Since(s)).
+			Dur("ttl", in.TTL).
+			Str("url", in.Meta.URL.String()).
+			Str("path", fileKey).
+			Msg("upload")
+	}(time.Now())
+
+	// file is written before link, so link never points to missing file
+	if err := writeFileAtomic(s.path(fileKey), in.Body); err != nil {
+		return nil, xerrors.Errorf("write file: %w", err)
+	}
+
+	staleTTL := int64(in.StaleTTL.Seconds())
+
+	link, err := json.Marshal(&localLink{
+		Latest:   fileKey,
+		TTL:      int64(in.TTL.Seconds()),
+		URLHash:  HashURL(in.Meta.URL),
+		StaleTTL: &staleTTL,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("marshal link: %w", err)
+	}
+
+	if err := writeFileAtomic(s.path(linkKey), bytes.NewReader(link)); err != nil {
+		return nil, xerrors.Errorf("write link file: %w", err)
+	}
+
+	return s.uploadedFile(fileKey, in.TTL), nil
+}
+
+func (s *Local) uploadedFile(fileKey string, ttl time.Duration) *File {
+	return &File{
+		Key: fileKey,
+		TTL: ttl,
+		URL: s.getURL(fileKey),
+	}
+}
+
+func (s *Local) uploadSnapshot(ctx context.Context, in Upload) (*File, error) {
+	fileKey := s.getSnapshotKey(in.Meta, in.Snapshot)
+
+	defer func(s time.Time) {
+		log.Ctx(ctx).Debug().
+			Dur("took", time.Since(s)).
+			Str("url", in.Meta.URL.String()).
+			Str("path", fileKey).
+			Msg("upload snapshot")
+	}(time.Now())
+
+	if err := writeFileAtomic(s.path(fileKey), in.Body); err != nil {
+		return nil, xerrors.Errorf("write file: %w", err)
+	}
+
+	return s.uploadedFile(fileKey, in.TTL), nil
+}
+
+// writeFileAtomic writes body to temporary file and renames it,
+// so readers never see half-written file.
+func writeFileAtomic(name string, body io.Reader) error {
+	dir := filepath.Dir(name)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return xerrors.Errorf("create dir: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(dir, filepath.Base(name)+".*"+tmpExt)
+	if err != nil {
+		return xerrors.Errorf("create temp file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return xerrors.Errorf("write temp file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return xerrors.Errorf("close temp file: %w", err)
+	}
+
+	// temp file is created with 0600
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return xerrors.Errorf("chmod temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return xerrors.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+// getRoot returns dir and name prefix of keys matching query.
+func (s *Local) getRoot(query Query) (string, string) {
+	switch {
+	case query.Meta != nil:
+		dir, hash := getKey(*query.Meta)
+		return dir, hash + "."
+	case query.Host != "":
+		return query.Host, ""
+	default:
+		return "", ""
+	}
+}
+
+func (s *Local) List(ctx context.Context, query Query) ([]*Object, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	dir, prefix := s.getRoot(query)
+
+	var objects []*Object
+
+	err := filepath.Walk(s.path(dir), func(name string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.dir, name)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if key == snapshotsDir {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !strings.HasPrefix(info.Name(), prefix) {
+			return nil
+		}
+
+		// temp files are listed, so files left by crash are collected
+		object := &Object{
+			Key:     key,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			Temp:    strings.HasSuffix(key, tmpExt),
+			Link:    strings.HasSuffix(key, linkExt),
+		}
+
+		if object.Link {
+			link, _, err := s.readLink(key)
+			if xerrors.Is(err, ErrFileNotFound) {
+				return nil
+			} else if err == nil {
+				object.Target = link.Latest
+				object.TTL = time.Duration(link.TTL) * time.Second
+				object.URLHash = link.URLHash
+
+				if link.StaleTTL != nil {
+					staleTTL := time.Duration(*link.StaleTTL) * time.Second
+					object.StaleTTL = &staleTTL
+				}
+			}
+
+			// corrupted link is treated as expired
+		}
+
+		objects = append(objects, object)
+
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("walk: %w", err)
+	}
+
+	return objects, nil
+}
+
+func (s *Local) Delete(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+			return xerrors.Errorf("remove '%s': %w", key, err)
+		}
+	}
+
+	return nil
+}
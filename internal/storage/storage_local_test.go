@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMeta(t *testing.T, rawURL string) Meta {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+
+	return Meta{URL: u, Opts: "opts"}
+}
+
+func uploadTest(t *testing.T, s Storage, meta Meta, body string, ttl time.Duration) *File {
+	t.Helper()
+
+	file, err := s.Upload(context.Background(), Upload{
+		Meta: meta,
+		TTL:  ttl,
+		Body: bytes.NewReader([]byte(body)),
+	})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	return file
+}
+
+func readTest(t *testing.T, s Storage, key string) string {
+	t.Helper()
+
+	r, err := s.Open(context.Background(), key)
+	if err != nil {
+		t.Fatalf("open '%s': %v", key, err)
+	}
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read '%s': %v", key, err)
+	}
+
+	return string(body)
+}
+
+func TestLocalUpload(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(dir, "https://cdn.example.com/")
+	meta := newTestMeta(t, "https://example.com/a")
+
+	first := uploadTest(t, local, meta, "first", time.Hour)
+	second := uploadTest(t, local, meta, "second", time.Hour)
+
+	file, err := local.Stat(context.Background(), meta)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+
+	if file.Key != second.Key || file.TTL != time.Hour || file.URL != "https://cdn.example.com/"+second.Key {
+		t.Errorf("stat returned %+v, expected latest file %+v", file, second)
+	}
+
+	if body := readTest(t, local, first.Key); body != "first" {
+		t.Errorf("body of first file is '%s', expected 'first'", body)
+	}
+
+	if body := readTest(t, local, file.Key); body != "second" {
+		t.Errorf("body of latest file is '%s', expected 'second'", body)
+	}
+
+	// temp files are renamed, so nothing is left after upload
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err == nil && strings.HasSuffix(name, tmpExt) {
+			t.Errorf("temp file '%s' is left", name)
+		}
+
+		return err
+	})
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+}
+
+func TestLocalUploadSnapshot(t *testing.T) {
+	local := NewLocal(t.TempDir(), "")
+	meta := newTestMeta(t, "https://example.com/a")
+
+	at := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	file, err := local.Upload(context.Background(), Upload{
+		Meta:     meta,
+		TTL:      time.Hour,
+		Body:     bytes.NewReader([]byte("snapshot")),
+		Snapshot: at,
+	})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	if !strings.HasPrefix(file.Key, snapshotsDir+"/example.com/") || !strings.HasSuffix(file.Key, "/20210701T120000Z.png") {
+		t.Errorf("unexpected key of snapshot '%s'", file.Key)
+	}
+
+	// snapshot doesn't replace cached file
+	if _, err := local.Stat(context.Background(), meta); err != ErrFileNotFound {
+		t.Errorf("stat returned %v, expected %v", err, ErrFileNotFound)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(filepath.Join(dir, "storage"), "")
+
+	if err := os.MkdirAll(filepath.Join(dir, "storage"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	for _, key := range []string{"../secret", "/../secret", "example.com/../../secret"} {
+		if path := local.path(key); !strings.HasPrefix(path, filepath.Join(dir, "storage")+string(filepath.Separator)) {
+			t.Errorf("path of '%s' is '%s', it's outside of storage", key, path)
+		}
+
+		if _, err := local.Open(context.Background(), key); err != ErrFileNotFound {
+			t.Errorf("open of '%s' returned %v, expected %v", key, err, ErrFileNotFound)
+		}
+	}
+}
+
+func TestLocalCorruptedLink(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(dir, "")
+	meta := newTestMeta(t, "https://example.com/a")
+
+	uploadTest(t, local, meta, "image", time.Hour)
+
+	linkKey := local.getLinkKey(meta)
+
+	if err := ioutil.WriteFile(local.path(linkKey), []byte("{"), 0o644); err != nil {
+		t.Fatalf("write link: %v", err)
+	}
+
+	if _, err := local.Stat(context.Background(), meta); err != ErrFileCorrupted {
+		t.Errorf("stat returned %v, expected %v", err, ErrFileCorrupted)
+	}
+
+	objects, err := local.List(context.Background(), Query{Host: "example.com"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	// corrupted link is listed without target, so it's treated as expired
+	found := false
+
+	for _, object := range objects {
+		if object.Key != linkKey {
+			continue
+		}
+
+		found = true
+
+		if !object.Link || object.Target != "" || !object.IsExpired(time.Now()) {
+			t.Errorf("unexpected corrupted link %+v", object)
+		}
+	}
+
+	if !found {
+		t.Errorf("corrupted link is not listed")
+	}
+}
+
+func TestLocalList(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(dir, "")
+
+	a := newTestMeta(t, "https://example.com/a")
+	b := newTestMeta(t, "https://example.org/b")
+
+	file := uploadTest(t, local, a, "a", time.Hour)
+	other := uploadTest(t, local, b, "b", time.Hour)
+
+	if _, err := local.Upload(context.Background(), Upload{
+		Meta:     a,
+		Body:     bytes.NewReader([]byte("snapshot")),
+		Snapshot: time.Now(),
+	}); err != nil {
+		t.Fatalf("upload snapshot: %v", err)
+	}
+
+	// temp file left by crashed upload
+	tmpKey := file.Key + ".123" + tmpExt
+
+	if err := ioutil.WriteFile(local.path(tmpKey), []byte("a"), 0o644); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+
+	keys := func(objects []*Object) []string {
+		result := make([]string, 0, len(objects))
+		for _, object := range objects {
+			result = append(result, object.Key)
+		}
+
+		sort.Strings(result)
+
+		return result
+	}
+
+	for _, test := range []struct {
+		name  string
+		query Query
+		keys  []string
+	}{
+		{"all", Query{}, []string{
+			file.Key,
+			tmpKey,
+			local.getLinkKey(a),
+			local.getLinkKey(b),
+			other.Key,
+		}},
+		{"meta", Query{Meta: &a}, []string{file.Key, tmpKey, local.getLinkKey(a)}},
+		{"host", Query{Host: "example.org"}, []string{local.getLinkKey(b), other.Key}},
+		{"missing host", Query{Host: "example.net"}, []string{}},
+	} {
+		objects, err := local.List(context.Background(), test.query)
+		if err != nil {
+			t.Fatalf("%s: list: %v", test.name, err)
+		}
+
+		got := keys(objects)
+		want := append([]string{}, test.keys...)
+		sort.Strings(want)
+
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: listed %v, expected %v", test.name, got, want)
+		}
+	}
+
+	for _, host := range []string{"..", ".", "example.com/..", `example.com\a`, "a..b"} {
+		if _, err := local.List(context.Background(), Query{Host: host}); err == nil {
+			t.Errorf("list of host '%s' is not rejected", host)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/xerrors"
+)
+
+// Memory is LRU cache of screenshots in front of other storage.
+// Its size is limited by total size of images.
+type Memory struct {
+	backend  Storage
+	maxBytes int64
+
+	hits   int64
+	misses int64
+
+	lock  sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List
+	bytes int64
+}
+
+type memoryItem struct {
+	// key of screenshot
+	key string
+
+	// latest file of screenshot, body is nil until file is opened
+	fileKey  string
+	body     []byte
+	uploaded time.Time
+	ttl      time.Duration
+	url      string
+}
+
+// size of item, metadata is counted too, so items without body are bounded
+func (item *memoryItem) size() int64 {
+	return int64(len(item.body) + len(item.key) + len(item.fileKey) + len(item.url))
+}
+
+func NewMemory(backend Storage, maxBytes int64) *Memory {
+	return &Memory{
+		backend:  backend,
+		maxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// MemoryStats is counters of memory cache.
+type MemoryStats struct {
+	Hits   int64
+	Misses int64
+	Items  int
+	Bytes  int64
+}
+
+func (s *Memory) Stats() MemoryStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return MemoryStats{
+		Hits:   atomic.LoadInt64(&s.hits),
+		Misses: atomic.LoadInt64(&s.misses),
+		Items:  len(s.items),
+		Bytes:  s.bytes,
+	}
+}
+
+// getMemoryKey returns key of screenshot, same for all files of it.
+func getMemoryKey(in Meta) string {
+	dir, hash := getKey(in)
+
+	return dir + "/" + hash
+}
+
+// getObjectMemoryKey returns key of screenshot by key of object in backend.
+func getObjectMemoryKey(key string) string {
+	return path.Base(path.Dir(key)) + "/" + objectHash(key)
+}
+
+// Stat returns cached file without request to backend, hits and misses are counted by it.
+func (s *Memory) Stat(ctx context.Context, in Meta) (*File, error) {
+	key := getMemoryKey(in)
+
+	if item := s.get(key); item != nil {
+		atomic.AddInt64(&s.hits, 1)
+
+		return &File{
+			Key: item.fileKey,
+			Age: time.Since(item.uploaded),
+			TTL: item.ttl,
+			URL: item.url,
+		}, nil
+	}
+
+	atomic.AddInt64(&s.misses, 1)
+
+	file, err := s.backend.Stat(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	// expired file is not cached, it's going to be replaced
+	if !file.IsExpired() {
+		s.put(&memoryItem{
+			key:      key,
+			fileKey:  file.Key,
+			uploaded: time.Now().Add(-file.Age),
+			ttl:      file.TTL,
+			url:      file.URL,
+		})
+	}
+
+	return file, nil
+}
+
+func (s *Memory) Open(ctx context.Context, key string) (io.Reader, error) {
+	if body := s.getBody(key); body != nil {
+		return bytes.NewReader(body), nil
+	}
+
+	r, err := s.backend.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, xerrors.Errorf("read file: %w", err)
+	}
+
+	s.attachBody(key, body)
+
+	return bytes.NewReader(body), nil
+}
+
+func (s *Memory) Upload(ctx context.Context, in Upload) (*File, error) {
+	if !in.Snapshot.IsZero() {
+		return s.backend.Upload(ctx, in)
+	}
+
+	body, err := ioutil.ReadAll(in.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("read body: %w", err)
+	}
+
+	in.Body = bytes.NewReader(body)
+
+	file, err := s.backend.Upload(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	s.put(&memoryItem{
+		key:      getMemoryKey(in.Meta),
+		fileKey:  file.Key,
+		body:     body,
+		uploaded: time.Now(),
+		ttl:      in.TTL,
+		url:      file.URL,
+	})
+
+	return file, nil
+}
+
+func (s *Memory) List(ctx context.Context, query Query) ([]*Object, error) {
+	return s.backend.List(ctx, query)
+}
+
+func (s *Memory) Delete(ctx context.Context, keys []string) error {
+	s.lock.Lock()
+
+	for _, key := range keys {
+		if el, ok := s.items[getObjectMemoryKey(key)]; ok {
+			s.remove(el)
+		}
+	}
+
+	s.lock.Unlock()
+
+	return s.backend.Delete(ctx, keys)
+}
+
+// get returns item, if it's cached and not expired.
+func (s *Memory) get(key string) *memoryItem {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+
+	item := el.Value.(*memoryItem)
+
+	if time.Since(item.uploaded) > item.ttl {
+		s.remove(el)
+		return nil
+	}
+
+	s.lru.MoveToFront(el)
+
+	return item
+}
+
+// getBody returns cached body of file.
+func (s *Memory) getBody(fileKey string) []byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	el, ok := s.items[getObjectMemoryKey(fileKey)]
+	if !ok {
+		return nil
+	}
+
+	item := el.Value.(*memoryItem)
+	if item.fileKey != fileKey {
+		return nil
+	}
+
+	s.lru.MoveToFront(el)
+
+	return item.body
+}
+
+// attachBody caches body of file, when file is still latest file of cached screenshot.
+// Check and replace are done under single lock, so item of concurrent upload is not overwritten.
+func (s *Memory) attachBody(fileKey string, body []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	el, ok := s.items[getObjectMemoryKey(fileKey)]
+	if !ok || el.Value.(*memoryItem).fileKey != fileKey {
+		return
+	}
+
+	item := *el.Value.(*memoryItem)
+	item.body = body
+
+	s.putLocked(&item)
+}
+
+// put adds or replaces item and evicts least recently used items to fit size limit.
+func (s *Memory) put(item *memoryItem) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.putLocked(item)
+}
+
+// putLocked is put, which should be called under lock.
+func (s *Memory) putLocked(item *memoryItem) {
+	size := item.size()
+
+	if el, ok := s.items[item.key]; ok {
+		s.remove(el)
+	}
+
+	if size > s.maxBytes {
+		return
+	}
+
+	for s.bytes+size > s.maxBytes {
+		s.remove(s.lru.Back())
+	}
+
+	s.items[item.key] = s.lru.PushFront(item)
+	s.bytes += size
+}
+
+// remove deletes item, should be called under lock.
+func (s *Memory) remove(el *list.Element) {
+	item := s.lru.Remove(el).(*memoryItem)
+
+	delete(s.items, item.key)
+	s.bytes -= item.size()
+}
+
+// LogStats logs counters with interval until ctx is done.
+func (s *Memory) LogStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stats := s.Stats()
+
+		log.Ctx(ctx).Info().
+			Int64("hits", stats.Hits).
+			Int64("misses", stats.Misses).
+			Int("items", stats.Items).
+			Int64("bytes", stats.Bytes).
+			Msg("memory cache stats")
+	}
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryUpload(t *testing.T) {
+	dir := t.TempDir()
+	memory := NewMemory(NewLocal(dir, "https://cdn.example.com"), 1<<20)
+	meta := newTestMeta(t, "https://example.com/a")
+
+	uploaded := uploadTest(t, memory, meta, "image", time.Hour)
+
+	file, err := memory.Stat(context.Background(), meta)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+
+	if file.Key != uploaded.Key || file.URL != uploaded.URL || file.URL == "" {
+		t.Errorf("stat returned %+v, expected file %+v", file, uploaded)
+	}
+
+	// body is served from memory
+	if err := os.Remove(filepath.Join(dir, filepath.FromSlash(file.Key))); err != nil {
+		t.Fatalf("remove file: %v", err)
+	}
+
+	if body := readTest(t, memory, file.Key); body != "image" {
+		t.Errorf("body is '%s', expected 'image'", body)
+	}
+
+	if stats := memory.Stats(); stats.Hits != 1 || stats.Misses != 0 || stats.Items != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestMemoryStatMiss(t *testing.T) {
+	backend := NewLocal(t.TempDir(), "")
+	memory := NewMemory(backend, 1<<20)
+	meta := newTestMeta(t, "https://example.com/a")
+
+	uploaded := uploadTest(t, backend, meta, "image", time.Hour)
+
+	for i := 0; i < 2; i++ {
+		file, err := memory.Stat(context.Background(), meta)
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+
+		if file.Key != uploaded.Key {
+			t.Errorf("key is '%s', expected '%s'", file.Key, uploaded.Key)
+		}
+	}
+
+	// body is loaded by first open and attached to metadata
+	if body := readTest(t, memory, uploaded.Key); body != "image" {
+		t.Errorf("body is '%s', expected 'image'", body)
+	}
+
+	if stats := memory.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Bytes < int64(len("image")) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if _, err := memory.Stat(context.Background(), newTestMeta(t, "https://example.com/b")); err != ErrFileNotFound {
+		t.Errorf("stat of missing file returned %v, expected %v", err, ErrFileNotFound)
+	}
+}
+
+func TestMemoryTTL(t *testing.T) {
+	memory := NewMemory(NewLocal(t.TempDir(), ""), 1<<20)
+	meta := newTestMeta(t, "https://example.com/a")
+
+	uploadTest(t, memory, meta, "image", time.Millisecond)
+
+	time.Sleep(time.Millisecond * 5)
+
+	// expired file is found in backend, but it's not cached
+	for i := 0; i < 2; i++ {
+		file, err := memory.Stat(context.Background(), meta)
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+
+		if !file.IsExpired() {
+			t.Errorf("file is not expired: %+v", file)
+		}
+	}
+
+	if stats := memory.Stats(); stats.Hits != 0 || stats.Misses != 2 || stats.Items != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestMemoryEvict(t *testing.T) {
+	backend := NewLocal(t.TempDir(), "")
+
+	a := newTestMeta(t, "https://example.com/a")
+	b := newTestMeta(t, "https://example.com/b")
+	c := newTestMeta(t, "https://example.com/c")
+
+	// size of item is body and keys, so limit fits two items
+	sample := uploadTest(t, NewMemory(backend, 1<<20), a, "0123456789", time.Hour)
+	limit := 2 * (int64(len("0123456789")) + int64(len(getMemoryKey(a))) + int64(len(sample.Key)))
+
+	for _, test := range []struct {
+		name    string
+		touch   bool
+		evicted Meta
+		kept    Meta
+	}{
+		{"least recently uploaded is evicted", false, a, b},
+		{"least recently used is evicted", true, b, a},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			memory := NewMemory(backend, limit)
+
+			uploadTest(t, memory, a, "0123456789", time.Hour)
+			uploadTest(t, memory, b, "0123456789", time.Hour)
+
+			if test.touch {
+				if _, err := memory.Stat(context.Background(), a); err != nil {
+					t.Fatalf("stat: %v", err)
+				}
+			}
+
+			uploadTest(t, memory, c, "0123456789", time.Hour)
+
+			if stats := memory.Stats(); stats.Items != 2 || stats.Bytes > limit {
+				t.Errorf("unexpected stats %+v, limit is %d", stats, limit)
+			}
+
+			if memory.get(getMemoryKey(test.evicted)) != nil {
+				t.Errorf("%s is not evicted", test.evicted.URL)
+			}
+
+			if memory.get(getMemoryKey(test.kept)) == nil || memory.get(getMemoryKey(c)) == nil {
+				t.Errorf("%s or %s is evicted", test.kept.URL, c.URL)
+			}
+		})
+	}
+}
+
+func TestMemoryTooLarge(t *testing.T) {
+	memory := NewMemory(NewLocal(t.TempDir(), ""), 4)
+	meta := newTestMeta(t, "https://example.com/a")
+
+	uploadTest(t, memory, meta, "0123456789", time.Hour)
+
+	if stats := memory.Stats(); stats.Items != 0 || stats.Bytes != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestMemoryDelete(t *testing.T) {
+	memory := NewMemory(NewLocal(t.TempDir(), ""), 1<<20)
+	meta := newTestMeta(t, "https://example.com/a")
+
+	file := uploadTest(t, memory, meta, "image", time.Hour)
+
+	if err := memory.Delete(context.Background(), []string{file.Key}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if stats := memory.Stats(); stats.Items != 0 || stats.Bytes != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if _, err := memory.Open(context.Background(), file.Key); err != ErrFileNotFound {
+		t.Errorf("open of deleted file returned %v, expected %v", err, ErrFileNotFound)
+	}
+}
+
+func TestMemoryAttachStaleBody(t *testing.T) {
+	memory := NewMemory(NewLocal(t.TempDir(), ""), 1<<20)
+	meta := newTestMeta(t, "https://example.com/a")
+
+	first := uploadTest(t, memory, meta, "first", time.Hour)
+	second := uploadTest(t, memory, meta, "second", time.Hour)
+
+	// body of replaced file read by concurrent open is not attached to latest file
+	memory.attachBody(first.Key, []byte("first"))
+
+	file, err := memory.Stat(context.Background(), meta)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+
+	if file.Key != second.Key {
+		t.Errorf("key is '%s', expected '%s'", file.Key, second.Key)
+	}
+
+	if body := memory.getBody(second.Key); string(body) != "second" {
+		t.Errorf("cached body is '%s', expected 'second'", body)
+	}
+}
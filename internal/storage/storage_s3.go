@@ -9,6 +9,7 @@ import (
 	"io"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -26,6 +27,8 @@ const (
 	defaultCacheControlMaxAge = 3600 * 24 * 360
 	fileMetadataLatestKey     = "Latest"
 	fileMetadataTTLKey        = "Ttl"
+	fileMetadataURLHashKey    = "Url-Hash"
+	fileMetadataStaleTTLKey   = "Stale-Ttl"
 	hashFirstChars            = 15
 )
 
@@ -36,9 +39,14 @@ type S3 struct {
 	subdir     string
 	uploader   *s3manager.Uploader
 	downloader *s3manager.Downloader
+
+	// base URL of files, e.g. CDN
+	publicURL string
 }
 
-func NewS3(s *session.Session, bucket string, subdir string) *S3 {
+// NewS3 creates storage in bucket subdir.
+// Files are served by publicURL, when it's empty, URL of bucket is used.
+func NewS3(s *session.Session, bucket string, subdir string, publicURL string) *S3 {
 	return &S3{
 		session:    s,
 		client:     s3.New(s),
@@ -46,7 +54,26 @@ func NewS3(s *session.Session, bucket string, subdir string) *S3 {
 		subdir:     subdir,
 		uploader:   s3manager.NewUploader(s),
 		downloader: s3manager.NewDownloader(s),
+		publicURL:  publicURL,
+	}
+}
+
+// getURL returns public URL of file, files are uploaded with public-read ACL.
+func (s *S3) getURL(key string) string {
+	if s.publicURL != "" {
+		return strings.TrimSuffix(s.publicURL, "/") + "/" + cleanKey(key)
+	}
+
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+
+	if err := req.Build(); err != nil {
+		return ""
 	}
+
+	return req.HTTPRequest.URL.String()
 }
 
 func parseMetadataTTL(md map[string]*string) (time.Duration, error) {
@@ -63,10 +90,25 @@ func parseMetadataTTL(md map[string]*string) (time.Duration, error) {
 	return time.Duration(ttlInt) * time.Second, nil
 }
 
-func (s *S3) Get(ctx context.Context, in Meta) (io.Reader, error) {
-	linkPath := s.getLinkPath(in)
+// parseMetadataStaleTTL returns stale window of link, nil when it's not saved.
+func parseMetadataStaleTTL(md map[string]*string) *time.Duration {
+	v, ok := md[fileMetadataStaleTTLKey]
+	if !ok {
+		return nil
+	}
 
-	buf := &aws.WriteAtBuffer{}
+	seconds, err := strconv.Atoi(aws.StringValue(v))
+	if err != nil {
+		return nil
+	}
+
+	staleTTL := time.Duration(seconds) * time.Second
+
+	return &staleTTL
+}
+
+func (s *S3) Stat(ctx context.Context, in Meta) (*File, error) {
+	linkPath := s.getLinkPath(in)
 
 	link, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucket),
@@ -90,47 +132,70 @@ func (s *S3) Get(ctx context.Context, in Meta) (io.Reader, error) {
 
 	lastModifed := *link.LastModified
 
-	if time.Now().After(lastModifed.Add(ttl)) {
-		return nil, ErrFileExpired
-	}
-
 	latestFilePath, ok := link.Metadata[fileMetadataLatestKey]
 	if !ok {
 		return nil, ErrFileCorrupted
 	}
 
-	_, err = s.downloader.DownloadWithContext(ctx, buf, &s3.GetObjectInput{
+	return &File{
+		Key: cleanKey(*latestFilePath),
+		Age: time.Since(lastModifed),
+		TTL: ttl,
+		URL: s.getURL(*latestFilePath),
+	}, nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.Reader, error) {
+	buf := &aws.WriteAtBuffer{}
+
+	_, err := s.downloader.DownloadWithContext(ctx, buf, &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
-		Key:    latestFilePath,
+		Key:    aws.String(key),
 	})
 
-	if err != nil {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrFileNotFound
+	} else if err != nil {
 		return nil, xerrors.Errorf("get object: %w", err)
 	}
 
-	return bytes.NewBuffer(buf.Bytes()), nil
+	return bytes.NewReader(buf.Bytes()), nil
 }
 
-func (s *S3) getLinkPath(in Meta) string {
+// contentDir groups screenshots of rendered HTML,
+// underscore is not allowed in hostname, so it can't clash with site dir.
+const contentDir = "_html"
+
+// getKey returns dir and hash of screenshot.
+// Screenshots of site are grouped by hostname.
+func getKey(in Meta) (string, string) {
 	h := sha256.New()
 	h.Write([]byte(in.URL.String()))
+	h.Write([]byte(in.Content))
 	h.Write([]byte(in.Opts))
 	hash := hex.EncodeToString(h.Sum(nil))
 
-	p := fmt.Sprintf("/%s/%s.link", in.URL.Hostname(), hash[:hashFirstChars])
+	if in.Content != "" {
+		return contentDir, hash[:hashFirstChars]
+	}
+
+	return in.URL.Hostname(), hash[:hashFirstChars]
+}
+
+func (s *S3) getLinkPath(in Meta) string {
+	dir, hash := getKey(in)
+
+	p := fmt.Sprintf("/%s/%s%s", dir, hash, linkExt)
 
 	return path.Join(s.subdir, p)
 }
 
 func (s *S3) getFilePath(in Meta) string {
-	h := sha256.New()
-	h.Write([]byte(in.URL.String()))
-	h.Write([]byte(in.Opts))
-	hash := hex.EncodeToString(h.Sum(nil))
+	dir, hash := getKey(in)
 
 	loc := fmt.Sprintf("/%s/%s.%s.%s",
-		in.URL.Hostname(),
-		hash[:hashFirstChars],
+		dir,
+		hash,
 		xid.New().String(),
 		in.Format.Ext(),
 	)
@@ -138,6 +203,25 @@ func (s *S3) getFilePath(in Meta) string {
 	return path.Join(s.subdir, loc)
 }
 
+// snapshotsDir contains snapshots, they are not referenced by links.
+const snapshotsDir = "_snapshots"
+
+const snapshotTimeLayout = "20060102T150405Z"
+
+func (s *S3) getSnapshotPath(in Meta, at time.Time) string {
+	dir, hash := getKey(in)
+
+	loc := fmt.Sprintf("/%s/%s/%s/%s.%s",
+		snapshotsDir,
+		dir,
+		hash,
+		at.UTC().Format(snapshotTimeLayout),
+		in.Format.Ext(),
+	)
+
+	return path.Join(s.subdir, loc)
+}
+
 func isS3NotFoundErr(err error) bool {
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
@@ -153,7 +237,11 @@ func isS3NotFoundErr(err error) bool {
 	return false
 }
 
-func (s *S3) Upload(ctx context.Context, in Upload) error {
+func (s *S3) Upload(ctx context.Context, in Upload) (*File, error) {
+	if !in.Snapshot.IsZero() {
+		return s.uploadSnapshot(ctx, in)
+	}
+
 	filePath := s.getFilePath(in.Meta)
 	linkPath := s.getLinkPath(in.Meta)
 
@@ -176,8 +264,10 @@ func (s *S3) Upload(ctx context.Context, in Upload) error {
 			Body:        bytes.NewBufferString(linkPath),
 			ContentType: aws.String("application/octet-stream"),
 			Metadata: aws.StringMap(map[string]string{
-				fileMetadataLatestKey: filePath,
-				fileMetadataTTLKey:    strconv.Itoa(int(in.TTL.Seconds())),
+				fileMetadataLatestKey:   filePath,
+				fileMetadataTTLKey:      strconv.Itoa(int(in.TTL.Seconds())),
+				fileMetadataURLHashKey:  HashURL(in.Meta.URL),
+				fileMetadataStaleTTLKey: strconv.Itoa(int(in.StaleTTL.Seconds())),
 			}),
 		})
 
@@ -209,5 +299,205 @@ func (s *S3) Upload(ctx context.Context, in Upload) error {
 		return nil
 	})
 
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return s.uploadedFile(filePath, in.TTL), nil
+}
+
+func (s *S3) uploadedFile(filePath string, ttl time.Duration) *File {
+	return &File{
+		Key: cleanKey(filePath),
+		TTL: ttl,
+		URL: s.getURL(filePath),
+	}
+}
+
+func (s *S3) uploadSnapshot(ctx context.Context, in Upload) (*File, error) {
+	filePath := s.getSnapshotPath(in.Meta, in.Snapshot)
+
+	defer func(s time.Time) {
+		log.Ctx(ctx).Debug().
+			Dur("took", time.Since(s)).
+			Str("url", in.Meta.URL.String()).
+			Str("path", filePath).
+			Msg("upload snapshot")
+	}(time.Now())
+
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(filePath),
+		Body:         in.Body,
+		CacheControl: aws.String(fmt.Sprintf("max-age=%d", defaultCacheControlMaxAge)),
+		ContentType:  aws.String(in.Meta.Format.ContentType()),
+		ACL:          aws.String("public-read"),
+	})
+
+	if err != nil {
+		return nil, xerrors.Errorf("upload: %w", err)
+	}
+
+	return s.uploadedFile(filePath, in.TTL), nil
+}
+
+const (
+	linkExt = ".link"
+
+	// s3 limits number of keys in single delete request
+	deleteBatchSize = 1000
+
+	// number of concurrent head requests of links
+	listHeadConcurrency = 16
+)
+
+// cleanKey returns key as it's stored in bucket.
+// SDK cleans path of request, so leading slash of key is dropped.
+func cleanKey(key string) string {
+	return strings.TrimPrefix(key, "/")
+}
+
+// getPrefix returns prefix of keys matching query.
+func (s *S3) getPrefix(query Query) string {
+	return cleanKey(s.getRawPrefix(query))
+}
+
+func (s *S3) getRawPrefix(query Query) string {
+	switch {
+	case query.Meta != nil:
+		dir, hash := getKey(*query.Meta)
+		return path.Join(s.subdir, "/"+dir, hash) + "."
+	case query.Host != "":
+		return path.Join(s.subdir, "/"+query.Host) + "/"
+	default:
+		return strings.TrimSuffix(path.Join(s.subdir, "/"), "/") + "/"
+	}
+}
+
+func (s *S3) List(ctx context.Context, query Query) ([]*Object, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	prefix := s.getPrefix(query)
+	snapshotsPrefix := cleanKey(path.Join(s.subdir, "/"+snapshotsDir) + "/")
+
+	var objects []*Object
+
+	if err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, item := range page.Contents {
+			key := aws.StringValue(item.Key)
+
+			if strings.HasPrefix(key, snapshotsPrefix) {
+				continue
+			}
+
+			objects = append(objects, &Object{
+				Key:     key,
+				Size:    aws.Int64Value(item.Size),
+				ModTime: aws.TimeValue(item.LastModified),
+				Link:    strings.HasSuffix(key, linkExt),
+			})
+		}
+
+		return true
+	}); err != nil {
+		return nil, xerrors.Errorf("list objects: %w", err)
+	}
+
+	if err := s.headLinks(ctx, objects); err != nil {
+		return nil, xerrors.Errorf("head links: %w", err)
+	}
+
+	return objects, nil
+}
+
+// headLinks loads metadata of links.
+func (s *S3) headLinks(ctx context.Context, objects []*Object) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	sem := make(chan struct{}, listHeadConcurrency)
+
+	for _, object := range objects {
+		if !object.Link {
+			continue
+		}
+
+		object := object
+
+		sem <- struct{}{}
+
+		g.Go(func() error {
+			defer func() { <-sem }()
+
+			link, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(s.bucket),
+				Key:    aws.String(object.Key),
+			})
+			if isS3NotFoundErr(err) {
+				return nil
+			} else if err != nil {
+				return xerrors.Errorf("head '%s': %w", object.Key, err)
+			}
+
+			object.Target = cleanKey(aws.StringValue(link.Metadata[fileMetadataLatestKey]))
+			object.URLHash = aws.StringValue(link.Metadata[fileMetadataURLHashKey])
+			object.StaleTTL = parseMetadataStaleTTL(link.Metadata)
+
+			// corrupted link is treated as expired
+			object.TTL, _ = parseMetadataTTL(link.Metadata)
+
+			return nil
+		})
+	}
+
 	return g.Wait()
 }
+
+// objectHash returns hash part of key, e.g. abc for host/abc.link and host/abc.xid.png.
+func objectHash(key string) string {
+	name := path.Base(key)
+
+	if idx := strings.Index(name, "."); idx != -1 {
+		return name[:idx]
+	}
+
+	return name
+}
+
+func (s *S3) Delete(ctx context.Context, keys []string) error {
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > deleteBatchSize {
+			n = deleteBatchSize
+		}
+
+		batch := make([]*s3.ObjectIdentifier, n)
+		for i, key := range keys[:n] {
+			batch[i] = &s3.ObjectIdentifier{Key: aws.String(key)}
+		}
+
+		out, err := s.client.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.bucket),
+			Delete: &s3.Delete{
+				Objects: batch,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return xerrors.Errorf("delete objects: %w", err)
+		}
+
+		if len(out.Errors) > 0 {
+			e := out.Errors[0]
+			return xerrors.Errorf("delete '%s': %s", aws.StringValue(e.Key), aws.StringValue(e.Message))
+		}
+
+		keys = keys[n:]
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrTemplateNotFound = xerrors.New("template not found")
+)
+
+const (
+	// sizeTemplateName is name of optional nested template defining size of screenshot,
+	// e.g. {{define "size"}}1200x630{{end}}
+	sizeTemplateName = "size"
+
+	// default size is recommended size of Open Graph image
+	defaultWidth  = 1200
+	defaultHeight = 630
+
+	templateExt = ".html"
+)
+
+var templateNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Template is parsed template file.
+type Template struct {
+	Name string
+
+	// Size of screenshot
+	Width, Height int
+
+	tmpl    *template.Template
+	modTime time.Time
+}
+
+// Execute renders template with data.
+func (tmpl *Template) Execute(data interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+
+	if err := tmpl.tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Dir loads templates from directory.
+// Template is reloaded when file is changed.
+type Dir struct {
+	path string
+
+	lock  sync.Mutex
+	cache map[string]*Template
+}
+
+func NewDir(path string) *Dir {
+	return &Dir{
+		path:  path,
+		cache: make(map[string]*Template),
+	}
+}
+
+// Get returns template by name, name is file name without extension.
+func (dir *Dir) Get(name string) (*Template, error) {
+	if !templateNameRegexp.MatchString(name) {
+		return nil, ErrTemplateNotFound
+	}
+
+	path := filepath.Join(dir.path, name+templateExt)
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, ErrTemplateNotFound
+	} else if err != nil {
+		return nil, xerrors.Errorf("stat template: %w", err)
+	}
+
+	dir.lock.Lock()
+	defer dir.lock.Unlock()
+
+	if tmpl, ok := dir.cache[name]; ok && tmpl.modTime.Equal(info.ModTime()) {
+		return tmpl, nil
+	}
+
+	tmpl, err := parse(name, path, info.ModTime())
+	if err != nil {
+		return nil, xerrors.Errorf("parse template '%s': %w", name, err)
+	}
+
+	dir.cache[name] = tmpl
+
+	return tmpl, nil
+}
+
+func parse(name string, path string, modTime time.Time) (*Template, error) {
+	tmpl, err := template.New(filepath.Base(path)).
+		Option("missingkey=zero").
+		ParseFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := defaultWidth, defaultHeight
+
+	if sizeTmpl := tmpl.Lookup(sizeTemplateName); sizeTmpl != nil {
+		buf := &bytes.Buffer{}
+
+		if err := sizeTmpl.Execute(buf, nil); err != nil {
+			return nil, xerrors.Errorf("execute size: %w", err)
+		}
+
+		if _, err := fmt.Sscanf(strings.TrimSpace(buf.String()), "%dx%d", &width, &height); err != nil {
+			return nil, xerrors.Errorf("invalid size '%s', expected WIDTHxHEIGHT", buf.String())
+		}
+	}
+
+	return &Template{
+		Name:    name,
+		Width:   width,
+		Height:  height,
+		tmpl:    tmpl,
+		modTime: modTime,
+	}, nil
+}
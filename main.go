@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,9 +18,12 @@ import (
 	"github.com/bots-house/webshot/internal"
 	"github.com/bots-house/webshot/internal/handler"
 	"github.com/bots-house/webshot/internal/handler/api"
+	"github.com/bots-house/webshot/internal/jobs"
 	"github.com/bots-house/webshot/internal/renderer"
+	"github.com/bots-house/webshot/internal/scheduler"
 	"github.com/bots-house/webshot/internal/service"
 	"github.com/bots-house/webshot/internal/storage"
+	"github.com/bots-house/webshot/internal/templates"
 	"github.com/getsentry/sentry-go"
 	"github.com/jessevdk/go-flags"
 	"github.com/rs/zerolog"
@@ -28,16 +33,27 @@ import (
 
 type Config struct {
 	Auth struct {
-		SignKey string `long:"sign-key" description:"require HMAC request signature" env:"SIGN_KEY"`
+		SignKey    string `long:"sign-key" description:"require HMAC request signature" env:"SIGN_KEY"`
+		AdminToken string `long:"admin-token" description:"enables admin API, token is passed as 'Authorization: Bearer <token>' header" env:"ADMIN_TOKEN"`
 	} `group:"Auth" namespace:"auth" env-namespace:"AUTH"`
 
 	HTTP struct {
 		Addr string `long:"addr" description:"http addr to listen" env:"ADDR" default:":8000"`
+
+		Response string `long:"response" description:"default response mode of cached image, redirect returns 302 to public URL of image" env:"RESPONSE" choice:"body" choice:"redirect" default:"body"`
 	} `group:"HTTP" namespace:"http" env-namespace:"HTTP"`
 
 	Browser struct {
 		Addr string            `long:"addr" description:"remote browser connection string. Allowed is ws://... or http://" env:"ADDR"`
 		Args map[string]string `long:"args" description:"extra local chrome command line args" env:"ARGS" env-delim:" "`
+
+		Timeout time.Duration `long:"timeout" description:"max render duration, timeout of request is capped by it" env:"TIMEOUT" default:"60s"`
+
+		Blocklist string `long:"blocklist" description:"path to hosts or EasyList-style domain blocklist file, requests to listed hosts are blocked" env:"BLOCKLIST"`
+
+		PoolSize       int `long:"pool-size" description:"number of long-lived browsers, zero means browser per request" env:"POOL_SIZE"`
+		PoolTabs       int `long:"pool-tabs" description:"max concurrent tabs per pooled browser" env:"POOL_TABS" default:"4"`
+		PoolMaxRenders int `long:"pool-max-renders" description:"recycle pooled browser after this number of renders, zero means never" env:"POOL_MAX_RENDERS" default:"100"`
 	} `group:"Browser" namespace:"browser" env-namespace:"BROWSER"`
 
 	Storage struct {
@@ -49,8 +65,53 @@ type Config struct {
 			Endpoint string `long:"endpoint" description:"s3 endpoint" env:"ENDPOINT"`
 			Subdir   string `long:"subdir" description:"s3 bucket subdir" env:"SUBDIR"`
 		} `group:"S3" namespace:"s3" env-namespace:"S3"`
+
+		Local struct {
+			Dir string `long:"dir" description:"path to directory to store screenshots in, used when s3 is not configured" env:"DIR"`
+		} `group:"Local" namespace:"local" env-namespace:"LOCAL"`
+
+		Memory struct {
+			Size          int           `long:"size" description:"max size in megabytes of in-memory LRU cache in front of storage, zero disables it" env:"SIZE"`
+			StatsInterval time.Duration `long:"stats-interval" description:"interval of logging hit and miss counts of in-memory cache" env:"STATS_INTERVAL" default:"5m"`
+		} `group:"Memory" namespace:"memory" env-namespace:"MEMORY"`
+
+		PublicURL string `long:"public-url" description:"base URL of stored images, e.g. CDN, used by redirect response. Default is URL of S3 bucket" env:"PUBLIC_URL"`
 	} `group:"Storage" namespace:"storage" env-namespace:"STORAGE"`
 
+	Templates struct {
+		Dir string `long:"dir" description:"path to directory with HTML templates, enables /template/{name}.{format} endpoint" env:"DIR"`
+	} `group:"Templates" namespace:"templates" env-namespace:"TEMPLATES"`
+
+	Cache struct {
+		StaleTTL time.Duration `long:"stale-ttl" description:"window after TTL, when expired image is served and refreshed in background, zero disables it" env:"STALE_TTL"`
+	} `group:"Cache" namespace:"cache" env-namespace:"CACHE"`
+
+	Jobs struct {
+		Workers   int           `long:"workers" description:"number of concurrent background renders, zero disables jobs API" env:"WORKERS" default:"2"`
+		QueueSize int           `long:"queue-size" description:"max number of queued jobs" env:"QUEUE_SIZE" default:"100"`
+		Retention time.Duration `long:"retention" description:"how long finished jobs are kept" env:"RETENTION" default:"1h"`
+
+		WebhookAttempts int           `long:"webhook-attempts" description:"max number of callback delivery attempts" env:"WEBHOOK_ATTEMPTS" default:"5"`
+		WebhookBackoff  time.Duration `long:"webhook-backoff" description:"delay before callback retry, doubled for each next attempt" env:"WEBHOOK_BACKOFF" default:"1s"`
+	} `group:"Jobs" namespace:"jobs" env-namespace:"JOBS"`
+
+	Batch struct {
+		Parallelism int `long:"parallelism" description:"number of concurrent renders of batch" env:"PARALLELISM" default:"4"`
+		MaxItems    int `long:"max-items" description:"max number of items in batch" env:"MAX_ITEMS" default:"200"`
+	} `group:"Batch" namespace:"batch" env-namespace:"BATCH"`
+
+	Scheduler struct {
+		File          string `long:"file" description:"JSON file with scheduled captures, enables scheduler. Changes of admin API and last runs are saved to it" env:"FILE"`
+		CatchUp       string `long:"catch-up" description:"what to do with runs missed during downtime" env:"CATCH_UP" choice:"skip" choice:"once" default:"skip"`
+		MaxConcurrent int    `long:"max-concurrent" description:"max number of concurrent scheduled captures" env:"MAX_CONCURRENT" default:"2"`
+	} `group:"Scheduler" namespace:"scheduler" env-namespace:"SCHEDULER"`
+
+	GC struct {
+		Interval time.Duration `long:"interval" description:"interval of background garbage collection of storage, zero disables it" env:"INTERVAL"`
+		MinAge   time.Duration `long:"min-age" description:"min age of image, which is not pointed by link, to delete it" env:"MIN_AGE" default:"1h"`
+		DryRun   bool          `long:"dry-run" description:"only report objects to delete" env:"DRY_RUN"`
+	} `group:"GC" namespace:"gc" env-namespace:"GC"`
+
 	Log struct {
 		Pretty bool `long:"pretty" description:"enable pretty logging" env:"PRETTY"`
 		Debug  bool `long:"debug" description:"enable debug level" env:"DEBUG"`
@@ -64,7 +125,12 @@ type Config struct {
 
 	Healthcheck bool `long:"healthcheck" description:"do healthcheck and exit if failure"`
 
+	GCCommand struct{} `command:"gc" description:"delete orphaned and expired images from storage and exit"`
+
 	Port int `long:"port" description:"port to listen, used by Heroku" env:"PORT" hidden:"true"`
+
+	// name of command to run instead of server
+	command string
 }
 
 func (cfg *Config) compute() {
@@ -77,6 +143,8 @@ func loadConfig() Config {
 	config := Config{}
 
 	parser := flags.NewParser(&config, flags.Default)
+	parser.SubcommandsOptional = true
+
 	if _, err := parser.Parse(); err != nil {
 		switch flagsErr := err.(type) {
 		case flags.ErrorType:
@@ -91,6 +159,10 @@ func loadConfig() Config {
 		}
 	}
 
+	if parser.Active != nil {
+		config.command = parser.Active.Name
+	}
+
 	config.compute()
 
 	return config
@@ -127,6 +199,15 @@ func main() {
 		return
 	}
 
+	if config.command == "gc" {
+		if err := runGC(ctx, config); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%+v\n", err)
+			defer os.Exit(2)
+		}
+
+		return
+	}
+
 	log.Ctx(ctx).Info().
 		Dict("build", zerolog.Dict().
 			Str("version", buildVersion).
@@ -167,6 +248,60 @@ func runHealthcheck(ctx context.Context, config Config) error {
 	return nil
 }
 
+func runGC(ctx context.Context, config Config) error {
+	storage, err := newStorage(ctx, config)
+	if err != nil {
+		return xerrors.Errorf("new storage: %w", err)
+	}
+
+	if storage == nil {
+		return xerrors.New("storage is not configured")
+	}
+
+	if isWholeBucket(config) {
+		log.Ctx(ctx).Warn().
+			Str("bucket", config.Storage.S3.Bucket).
+			Msg("s3 subdir is not set, whole bucket is scanned, only keys of links and images are deleted")
+	}
+
+	gc := newGC(storage, config)
+
+	report, err := gc.Collect(ctx)
+	if err != nil {
+		return xerrors.Errorf("collect: %w", err)
+	}
+
+	action := "deleted"
+	if gc.DryRun {
+		action = "to delete (dry run)"
+	}
+
+	fmt.Printf("scanned %d objects, %s %d expired and %d orphaned, kept %d foreign, %d bytes reclaimed\n",
+		report.Scanned,
+		action,
+		report.Expired,
+		report.Orphaned,
+		report.Foreign,
+		report.Bytes,
+	)
+
+	return nil
+}
+
+// isWholeBucket reports whether storage is s3 bucket without subdir, which can be shared with other files.
+func isWholeBucket(config Config) bool {
+	return config.Storage.S3.Key != "" && strings.Trim(config.Storage.S3.Subdir, "/") == ""
+}
+
+func newGC(s storage.Storage, config Config) *storage.GC {
+	return &storage.GC{
+		Storage:  s,
+		StaleTTL: config.Cache.StaleTTL,
+		MinAge:   config.GC.MinAge,
+		DryRun:   config.GC.DryRun,
+	}
+}
+
 func runServer(ctx context.Context, config Config) error {
 	storage, err := newStorage(ctx, config)
 	if err != nil {
@@ -178,17 +313,34 @@ func runServer(ctx context.Context, config Config) error {
 		return xerrors.Errorf("new renderer: %w", err)
 	}
 
+	if closer, ok := renderer.(io.Closer); ok {
+		defer closer.Close()
+	}
+
 	srv := &service.Service{
 		Renderer: renderer,
 		Storage:  storage,
+		StaleTTL: config.Cache.StaleTTL,
 	}
 
-	var apiAuth api.Auth
+	if config.Templates.Dir != "" {
+		log.Ctx(ctx).Info().Str("dir", config.Templates.Dir).Msg("templates are enabled")
+
+		srv.Templates = templates.NewDir(config.Templates.Dir)
+	}
+
+	var (
+		apiAuth api.Auth
+		signer  jobs.Signer
+	)
 
 	if config.Auth.SignKey != "" {
 		log.Ctx(ctx).Info().Msg("sign key is provided, auth is required")
 
-		apiAuth = api.NewAuthHMAC(config.Auth.SignKey, "sign")
+		authHMAC := api.NewAuthHMAC(config.Auth.SignKey, "sign")
+
+		apiAuth = authHMAC
+		signer = authHMAC
 	} else {
 		log.Ctx(ctx).Warn().Msg("sign key is not provided, auth is not required")
 	}
@@ -218,11 +370,83 @@ func runServer(ctx context.Context, config Config) error {
 		defer sentry.Flush(sentryFlushTimeout)
 	}
 
+	if storage != nil && config.GC.Interval > 0 {
+		if isWholeBucket(config) {
+			return xerrors.New("background gc of whole s3 bucket is refused, set --storage.s3.subdir or run gc command")
+		}
+
+		log.Ctx(ctx).Info().
+			Dur("interval", config.GC.Interval).
+			Bool("dry_run", config.GC.DryRun).
+			Msg("init storage gc")
+
+		go newGC(storage, config).Run(ctx, config.GC.Interval)
+	}
+
+	var queue *jobs.Queue
+
+	if config.Jobs.Workers > 0 {
+		log.Ctx(ctx).Info().
+			Int("workers", config.Jobs.Workers).
+			Int("queue_size", config.Jobs.QueueSize).
+			Msg("init jobs queue")
+
+		queue = jobs.NewQueue(srv, jobs.NewMemoryStore(config.Jobs.Retention), jobs.QueueOpts{
+			Workers: config.Jobs.Workers,
+			Size:    config.Jobs.QueueSize,
+			Webhook: &jobs.Webhook{
+				Signer:   signer,
+				Attempts: config.Jobs.WebhookAttempts,
+				Backoff:  config.Jobs.WebhookBackoff,
+			},
+		})
+
+		go queue.Run(ctx)
+	}
+
+	var sched *scheduler.Scheduler
+
+	if config.Scheduler.File != "" {
+		if storage == nil {
+			return xerrors.New("scheduler requires storage")
+		}
+
+		log.Ctx(ctx).Info().
+			Str("file", config.Scheduler.File).
+			Str("catch_up", config.Scheduler.CatchUp).
+			Msg("init scheduler")
+
+		sched, err = scheduler.New(ctx, srv, api.ParseShotParams, scheduler.Opts{
+			File:          config.Scheduler.File,
+			CatchUp:       scheduler.CatchUp(config.Scheduler.CatchUp),
+			MaxConcurrent: config.Scheduler.MaxConcurrent,
+		})
+		if err != nil {
+			return xerrors.Errorf("new scheduler: %w", err)
+		}
+
+		go sched.Run(ctx)
+	}
+
+	var adminAuth api.Auth
+
+	if config.Auth.AdminToken != "" {
+		adminAuth = api.NewAuthToken(config.Auth.AdminToken)
+	}
+
 	builder := handler.Builder{
 		Service:   srv,
+		Jobs:      queue,
 		Auth:      apiAuth,
 		BuildInfo: buildInfo,
 		Sentry:    config.Sentry.DSN != "",
+		Scheduler: sched,
+		AdminAuth: adminAuth,
+		Response:  api.ResponseMode(config.HTTP.Response),
+		Batch: api.BatchOpts{
+			Parallelism: config.Batch.Parallelism,
+			MaxItems:    config.Batch.MaxItems,
+		},
 	}
 
 	return listenAndServe(ctx, config.HTTP.Addr, builder.Build())
@@ -293,12 +517,69 @@ func newRenderer(ctx context.Context, cfg Config) (renderer.Renderer, error) {
 		log.Ctx(ctx).Info().Msg("init local chrome renderer")
 	}
 
-	return &renderer.Chrome{Resolver: resolver, Args: cfg.Browser.Args}, nil
+	chrome := &renderer.Chrome{
+		Resolver: resolver,
+		Args:     cfg.Browser.Args,
+		Timeout:  cfg.Browser.Timeout,
+	}
+
+	if cfg.Browser.Blocklist != "" {
+		blocklist, err := renderer.LoadBlocklistFile(cfg.Browser.Blocklist)
+		if err != nil {
+			return nil, xerrors.Errorf("load blocklist '%s': %w", cfg.Browser.Blocklist, err)
+		}
+
+		log.Ctx(ctx).Info().
+			Str("path", cfg.Browser.Blocklist).
+			Int("hosts", blocklist.Len()).
+			Msg("blocklist loaded")
+
+		chrome.Blocklist = blocklist
+	}
+
+	if cfg.Browser.PoolSize > 0 {
+		log.Ctx(ctx).Info().
+			Int("size", cfg.Browser.PoolSize).
+			Int("tabs", cfg.Browser.PoolTabs).
+			Int("max_renders", cfg.Browser.PoolMaxRenders).
+			Msg("init browser pool")
+
+		return renderer.NewChromePool(ctx, chrome, renderer.ChromePoolOpts{
+			Size:       cfg.Browser.PoolSize,
+			MaxTabs:    cfg.Browser.PoolTabs,
+			MaxRenders: cfg.Browser.PoolMaxRenders,
+		}), nil
+	}
+
+	return chrome, nil
+}
+
+func newStorage(ctx context.Context, cfg Config) (storage.Storage, error) {
+	backend, err := newBackendStorage(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if backend == nil || cfg.Storage.Memory.Size <= 0 {
+		return backend, nil
+	}
+
+	log.Info().
+		Int("size_mb", cfg.Storage.Memory.Size).
+		Msg("init memory cache")
+
+	memory := storage.NewMemory(backend, int64(cfg.Storage.Memory.Size)<<20)
+
+	if cfg.Storage.Memory.StatsInterval > 0 {
+		go memory.LogStats(ctx, cfg.Storage.Memory.StatsInterval)
+	}
+
+	return memory, nil
 }
 
-func newStorage(_ context.Context, cfg Config) (storage.Storage, error) {
+func newBackendStorage(ctx context.Context, cfg Config) (storage.Storage, error) {
 	if cfg.Storage.S3.Key == "" {
-		return nil, nil
+		return newLocalStorage(ctx, cfg)
 	}
 
 	log.Info().
@@ -322,5 +603,21 @@ func newStorage(_ context.Context, cfg Config) (storage.Storage, error) {
 		return nil, xerrors.Errorf("aws new session: %w", err)
 	}
 
-	return storage.NewS3(awsSession, cfg.Storage.S3.Bucket, cfg.Storage.S3.Subdir), nil
+	return storage.NewS3(awsSession, cfg.Storage.S3.Bucket, cfg.Storage.S3.Subdir, cfg.Storage.PublicURL), nil
+}
+
+func newLocalStorage(_ context.Context, cfg Config) (storage.Storage, error) {
+	if cfg.Storage.Local.Dir == "" {
+		return nil, nil
+	}
+
+	log.Info().
+		Str("dir", cfg.Storage.Local.Dir).
+		Msg("init local storage")
+
+	if err := os.MkdirAll(cfg.Storage.Local.Dir, 0o755); err != nil {
+		return nil, xerrors.Errorf("create dir '%s': %w", cfg.Storage.Local.Dir, err)
+	}
+
+	return storage.NewLocal(cfg.Storage.Local.Dir, cfg.Storage.PublicURL), nil
 }